@@ -2,24 +2,49 @@ package apiv2
 
 import (
 	"bytes"
-	"encoding/base64"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"strings"
+	"time"
 )
 
 const (
-	baseURL   = "https://huggingface.co"
 	// TODO: generate user agent according to user's configuration
 	UserAgent = "hugger/v0.3.0; None; hf_hub/0.24.6; python/3.12.10; torch/2.4.1; tensorflow/2.17.0"
 )
 
 // Core structs for API operations
 type HuggingFaceClient struct {
+	// TokenSource authenticates every request; nil means the deprecated
+	// Token, then APIKey, and anonymous access without either.
+	TokenSource TokenSource
+	// Deprecated: use TokenSource, e.g. through WithToken.
 	APIKey string
-	Token  string
+	// Deprecated: use TokenSource, e.g. through WithToken.
+	Token string
+
+	// Endpoint of the Hub; empty means HF_ENDPOINT or DefaultEndpoint.
+	Endpoint string
+	// DatasetsServerEndpoint is used for dataset statistics.
+	DatasetsServerEndpoint string
+	// HTTPClient sends every request; nil means http.DefaultClient.
+	HTTPClient *http.Client
+	// RetryPolicy for failed requests; nil means DefaultRetryPolicy.
+	RetryPolicy *RetryPolicy
+	// CacheDir holds downloaded files; empty means HF_HUB_CACHE or $HF_HOME/hub.
+	CacheDir string
+	// Concurrency is how many files are transferred at once; 0 means one at a time.
+	Concurrency int
+	// Progress, when set, is told about the bytes of every transfer.
+	Progress ProgressFunc
+	// ChunkConnections above 1 downloads LFS files bigger than ChunkSize in
+	// that many parallel byte ranges; ChunkSize 0 means DefaultChunkSize.
+	ChunkConnections int
+	ChunkSize        int64
+
+	httpOptions httpOptions
 }
 
 type HFRepo struct {
@@ -34,26 +59,45 @@ type HFFile struct {
 	Oid  string `json:"oid"`
 	Size uint   `json:"size"`
 	Path string `json:"path"`
+	LFS  *HFLfs `json:"lfs,omitempty"`
+	// LastCommit is only returned by expanded listings.
+	LastCommit *HFLastCommit `json:"lastCommit,omitempty"`
+}
+
+// HFLastCommit is the last commit that touched a file or directory.
+type HFLastCommit struct {
+	ID    string    `json:"id"`
+	Title string    `json:"title"`
+	Date  time.Time `json:"date"`
+}
+
+// HFLfs is set on files stored in Git LFS; Oid is the sha256 of the content.
+type HFLfs struct {
+	Oid         string `json:"oid"`
+	Size        int64  `json:"size"`
+	PointerSize int64  `json:"pointerSize"`
 }
 
 type UFile struct {
 	Path   string `json:"path"`
 	Sample string `json:"sample"`
-	Size   int    `json:"size"`
+	Size   int64  `json:"size"`
 }
 
 type UFiles struct {
 	Files []UFile `json:"files"`
 }
 
+// UFileMode tells how the Hub wants a file uploaded: "regular" (inline in
+// the commit) or "lfs".
+type UFileMode struct {
+	Path         string `json:"path"`
+	UploadMode   string `json:"uploadMode"`
+	ShouldIgnore bool   `json:"shouldIgnore"`
+}
+
 type UFileResponse struct {
-	Files []struct {
-		Path string `json:"path"`
-		Blob struct {
-			Size int    `json:"size"`
-			Oid  string `json:"oid"`
-		} `json:"blob"`
-	} `json:"files"`
+	Files []UFileMode `json:"files"`
 }
 
 type KeyValue struct {
@@ -61,13 +105,29 @@ type KeyValue struct {
 	Value interface{} `json:"value"`
 }
 
-func NewHuggingFaceClient(apiKey string) *HuggingFaceClient {
-	return &HuggingFaceClient{APIKey: apiKey}
+func NewHuggingFaceClient(apiKey string, opts ...ClientOption) *HuggingFaceClient {
+	client := &HuggingFaceClient{}
+	if apiKey != "" {
+		client.TokenSource = StaticToken(apiKey)
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+	client.applyHTTPOptions()
+	return client
 }
 
 // Core API methods
+//
+// Every method has a ...Context variant that takes a context.Context;
+// the plain variants use context.Background(). Methods that take a revision
+// accept a branch, tag or commit hash; an empty revision means DefaultRevision.
 func (client *HuggingFaceClient) CreateRepo(repoType, datasetName string, private bool) error {
-	url := fmt.Sprintf("%s/api/repos/create", baseURL)
+	return client.CreateRepoContext(context.Background(), repoType, datasetName, private)
+}
+
+func (client *HuggingFaceClient) CreateRepoContext(ctx context.Context, repoType, datasetName string, private bool) error {
+	url := fmt.Sprintf("%s/api/repos/create", client.endpoint())
 	parts := strings.Split(datasetName, "/")
 	if len(parts) != 2 {
 		return fmt.Errorf("repo name must be in format 'username/repo-name'")
@@ -81,228 +141,163 @@ func (client *HuggingFaceClient) CreateRepo(repoType, datasetName string, privat
 	}
 
 	data, _ := json.Marshal(payload)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(data))
 	if err != nil {
-		return fmt.Errorf("could not create repository request: %v", err)
+		return fmt.Errorf("could not create repository request: %w", err)
+	}
+	if err := client.authorize(req); err != nil {
+		return err
 	}
-	req.Header.Set("Authorization", "Bearer "+client.APIKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	_, err = client.doRequest(req)
+	resp, err := client.doRequestRetry(req, retryRejected)
 	if err != nil {
-		return err //fmt.Errorf("%v", err)
+		return err
 	}
-	fmt.Println("✨ Success! Your new repo is ready for action.")
+	resp.Body.Close()
 	return nil
 }
 
-func (client *HuggingFaceClient) UploadFile(repoType, datasetName, filePath string, contents []byte) error {
-	// Pre-upload request
-	url := fmt.Sprintf("%s/api/%s/%s/preupload/main", baseURL, repoType+"s", datasetName)
+func (client *HuggingFaceClient) UploadFile(repoType, datasetName, revision, filePath string, contents []byte) error {
+	return client.UploadFileContext(context.Background(), repoType, datasetName, revision, filePath, contents)
+}
 
-	contents64 := base64.StdEncoding.EncodeToString(contents)
-	ufiles := UFiles{
-		Files: []UFile{
-			{Path: filePath, Sample: contents64, Size: len(contents)},
-		},
-	}
+func (client *HuggingFaceClient) UploadFileContext(ctx context.Context, repoType, datasetName, revision, filePath string, contents []byte) error {
+	commit := client.NewCommit(repoType, datasetName, revision, "Uploading "+filePath).AddFile(filePath, contents)
+	return pushSingleUpload(ctx, commit, filePath)
+}
 
-	data, _ := json.Marshal(ufiles)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
+// UploadLocalFile uploads the file at localPath as filePath without reading it into memory,
+// which is what model weights and large parquet shards need.
+func (client *HuggingFaceClient) UploadLocalFile(repoType, datasetName, revision, filePath, localPath string) error {
+	return client.UploadLocalFileContext(context.Background(), repoType, datasetName, revision, filePath, localPath)
+}
+
+func (client *HuggingFaceClient) UploadLocalFileContext(ctx context.Context, repoType, datasetName, revision, filePath, localPath string) error {
+	commit := client.NewCommit(repoType, datasetName, revision, "Uploading "+filePath).AddLocalFile(filePath, localPath)
+	return pushSingleUpload(ctx, commit, filePath)
+}
+
+func pushSingleUpload(ctx context.Context, commit *CommitBuilder, filePath string) error {
+	info, err := commit.Push(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to prepare upload: %v", err)
+		return fmt.Errorf("file upload failed: %w", err)
 	}
-	req.Header.Set("Authorization", "Bearer "+client.APIKey)
-	req.Header.Set("Content-Type", "application/json")
+	if len(info.Ignored) > 0 {
+		fmt.Printf("🙈 %s is ignored by the repository's .gitignore, skipping\n", filePath)
+		return nil
+	}
+	fmt.Println("🚀 File uploaded successfully!")
+	return nil
+}
 
-	resp, err := client.doRequest(req)
-	if err != nil {
-		return fmt.Errorf("pre-upload request failed: %v", err)
+// preupload asks the Hub whether each file goes to LFS or inline into the commit.
+func (client *HuggingFaceClient) preupload(ctx context.Context, repoType, datasetName, revision string, createPR bool, ufiles UFiles) (map[string]UFileMode, error) {
+	url := fmt.Sprintf("%s/api/%s/%s/preupload/%s", client.endpoint(), repoType+"s", datasetName, escapeRevision(revision))
+	if createPR {
+		url += "?create_pr=1"
 	}
-	defer resp.Body.Close()
 
-	// Commit file upload
-	url = fmt.Sprintf("%s/api/%s/%s/commit/main", baseURL, repoType+"s", datasetName)
-	kv := KeyValue{
-		Key: "header",
-		Value: map[string]string{
-			"summary":     "Uploading " + filePath,
-			"description": "",
-		},
+	data, _ := json.Marshal(ufiles)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare upload: %w", err)
 	}
-	data, _ = json.Marshal(kv)
-	kv = KeyValue{
-		Key: "file",
-		Value: map[string]string{
-			"content":  contents64,
-			"path":     filePath,
-			"encoding": "base64",
-		},
+	if err := client.authorize(req); err != nil {
+		return nil, err
 	}
-	tmp, _ := json.Marshal(kv)
-	data = append(data, 0x0a)
-	data = append(data, tmp...)
+	req.Header.Set("Content-Type", "application/json")
 
-	req, err = http.NewRequest("POST", url, bytes.NewBuffer(data))
+	resp, err := client.doRequestRetry(req, retryIdempotent)
 	if err != nil {
-		return fmt.Errorf("file upload request failed: %v", err)
+		return nil, fmt.Errorf("pre-upload request failed: %w", err)
 	}
-	req.Header.Set("Authorization", "Bearer "+client.APIKey)
-	req.Header.Set("Content-Type", "application/x-ndjson")
+	defer resp.Body.Close()
 
-	_, err = client.doRequest(req)
-	if err != nil {
-		return fmt.Errorf("file upload failed: %v", err)
+	var response UFileResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode pre-upload response: %w", err)
 	}
-	fmt.Println("🚀 File uploaded successfully!")
-	return nil
+	modes := make(map[string]UFileMode, len(response.Files))
+	for _, mode := range response.Files {
+		modes[mode.Path] = mode
+	}
+	return modes, nil
 }
 
+// doRequest sends req and retries it if its method is idempotent.
 func (client *HuggingFaceClient) doRequest(req *http.Request) (*http.Response, error) {
+	return client.doRequestRetry(req, defaultRetryMode(req.Method))
+}
+
+func (client *HuggingFaceClient) sendRequest(req *http.Request) (*http.Response, error) {
 	req.Header.Set("User-Agent", UserAgent)
-	clientHTTP := &http.Client{}
-	resp, err := clientHTTP.Do(req)
+	resp, err := client.httpClient().Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %v", err)
+		return nil, fmt.Errorf("request failed: %w", err)
 	}
-	if resp.StatusCode != http.StatusOK {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("received error: %s", body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, newHubError(resp)
 	}
 	return resp, nil
 }
 
-func (client *HuggingFaceClient) DownloadFile(repoType, repoName, filePath string) ([]byte, error) {
-	url := fmt.Sprintf("%s/%s/%s/resolve/main/%s", baseURL, repoType+"s", repoName, filePath)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create download request: %v", err)
-	}
-	req.Header.Set("Authorization", "Bearer "+client.Token)
+func (client *HuggingFaceClient) DownloadFile(repoType, repoName, revision, filePath string) ([]byte, error) {
+	return client.DownloadFileContext(context.Background(), repoType, repoName, revision, filePath)
+}
 
-	resp, err := client.doRequest(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to download file: %v", err)
+// DownloadFileContext loads the whole file into memory; use DownloadToPathContext
+// or DownloadToWriterContext for large files.
+func (client *HuggingFaceClient) DownloadFileContext(ctx context.Context, repoType, repoName, revision, filePath string) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := client.DownloadToWriterContext(ctx, repoType, repoName, revision, filePath, &buf); err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	return ioutil.ReadAll(resp.Body)
+	return buf.Bytes(), nil
 }
 
 func (client *HuggingFaceClient) DeleteRepo(repoName string) error {
-	url := fmt.Sprintf("%s/api/repos/delete", baseURL)
+	return client.DeleteRepoContext(context.Background(), repoName)
+}
+
+func (client *HuggingFaceClient) DeleteRepoContext(ctx context.Context, repoName string) error {
+	url := fmt.Sprintf("%s/api/repos/delete", client.endpoint())
 	payload := map[string]string{"name": repoName}
 	data, _ := json.Marshal(payload)
 
-	req, err := http.NewRequest("DELETE", url, bytes.NewBuffer(data))
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, bytes.NewBuffer(data))
 	if err != nil {
-		return fmt.Errorf("failed to create delete request: %v", err)
+		return fmt.Errorf("failed to create delete request: %w", err)
 	}
-	req.Header.Set("Authorization", "Bearer "+client.Token)
-	req.Header.Set("Content-Type", "application/json")
-
-	_, err = client.doRequest(req)
-	return err
-}
-
-func (client *HuggingFaceClient) DeleteFile(repoType, repoName, filePath string) error {
-	url := fmt.Sprintf("%s/api/%s/%s/commit/main", baseURL, repoType + "s", repoName)
-
-	kv := KeyValue{
-		"header",
-		map[string]string {
-			"summary": "Delete " + filePath,
-			"description": "",
-		},
-	}
-	data, _ := json.Marshal( kv )
-	kv = KeyValue{
-		"deletedFile",
-		map[string]string {
-			"path": filePath,
-		},
-	}
-	tmp, _ := json.Marshal( kv )
-	data = append( data, 0x0a )
-	data = append(data, tmp...)
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
-	if err != nil {
+	if err := client.authorize(req); err != nil {
 		return err
 	}
-	req.Header.Set("Authorization", "Bearer " + client.APIKey)
-	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := client.doRequest(req)
+	resp, err := client.doRequestRetry(req, retryRejected)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-	_, err = ioutil.ReadAll( resp.Body )
-	return err
+	return resp.Body.Close()
 }
 
-func (client *HuggingFaceClient) ListFilesInRepo(repoType, repoName, path string, recursive bool) ([]string, error) {
-	// please, do not touch this function.
-	// yes, I know huggingface API has more beautiful way to list files
-	// however when I try to use it I'm getting error 404:
-	// 'Sorry, we can't find the page you are looking for.'
-	
-	// I'll fix this issue as soon as I understand what I'm doing wrong.
-
-	url := fmt.Sprintf("%s/api/%s/%s/tree/main", baseURL, repoType + "s", repoName)
-	if len(path) > 0 {
-		url += "/" + path
-	}
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Authorization", "Bearer " + client.APIKey)
-	clientHTTP := &http.Client{}
-	resp, err := clientHTTP.Do( req )
-	if err != nil {
-		return nil, err
-	}
-
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		body, _ := ioutil.ReadAll( resp.Body )
-		fmt.Println(string(body))
-		return nil, fmt.Errorf("error: received status code %d, response: %s",
-				resp.StatusCode, body)
-	}
-
-	body, err := ioutil.ReadAll( resp.Body )
-	if err != nil {
-		fmt.Println(string(body))
-		return nil, err
-	}
-
-	var files []HFFile
-	if err := json.Unmarshal( body, &files ); err != nil {
-		return nil, err
-	}
-
-	var totalFiles []string
-	for _, f := range files {
-		if f.Type == "file" {
-			totalFiles = append( totalFiles, f.Path )
-		} else if f.Type == "directory" {
-			if recursive {
-				dirFiles, err := client.ListFilesInRepo( repoType, repoName, f.Path, recursive )
-				if err != nil {
-					return nil, err
-				}
-				totalFiles = append( totalFiles, dirFiles... )
-			} else {
-				totalFiles = append( totalFiles, f.Path + "/" )
-			}
-		}
-	}
+func (client *HuggingFaceClient) DeleteFile(repoType, repoName, revision, filePath string) error {
+	return client.DeleteFileContext(context.Background(), repoType, repoName, revision, filePath)
+}
 
-	return totalFiles, nil
+func (client *HuggingFaceClient) DeleteFileContext(ctx context.Context, repoType, repoName, revision, filePath string) error {
+	_, err := client.NewCommit(repoType, repoName, revision, "Delete "+filePath).DeleteFile(filePath).Push(ctx)
+	return err
+}
 
+// ListFilesInRepo lists the files and directories under path at revision,
+// with their size, oid, LFS information and last commit. With recursive the
+// content of every directory below path is listed too.
+func (client *HuggingFaceClient) ListFilesInRepo(repoType, repoName, revision, path string, recursive bool) ([]HFFile, error) {
+	return client.ListFilesInRepoContext(context.Background(), repoType, repoName, revision, path, recursive)
+}
 
+func (client *HuggingFaceClient) ListFilesInRepoContext(ctx context.Context, repoType, repoName, revision, path string, recursive bool) ([]HFFile, error) {
+	return client.listTree(ctx, repoType, repoName, revision, path, recursive, true)
 }
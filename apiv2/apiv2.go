@@ -1,6 +1,7 @@
 package apiv2
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -26,26 +27,41 @@ type MetadataResponse struct {
 	AlternateName []string         `json:"alternateName,omitempty"`
 }
 
-func (client *HuggingFaceClient) GetMetadata(repoType, repoID string) (*MetadataResponse, error) {
+func (client *HuggingFaceClient) GetMetadata(repoType, repoID, revision string) (*MetadataResponse, error) {
+	return client.GetMetadataContext(context.Background(), repoType, repoID, revision)
+}
+
+func (client *HuggingFaceClient) GetMetadataContext(ctx context.Context, repoType, repoID, revision string) (*MetadataResponse, error) {
+	if revision != "" && revision != DefaultRevision {
+		// Croissant metadata is only served for the default branch
+		url := fmt.Sprintf("%s/api/%s/%s/revision/%s", client.endpoint(), repoType+"s", repoID, escapeRevision(revision))
+		return client.fetchMetadata(ctx, url)
+	}
+
 	// Try Croissant endpoint first
-	croissantURL := fmt.Sprintf("%s/api/%s/%s/croissant", baseURL, repoType+"s", repoID)
-	metadata, err := client.fetchMetadata(croissantURL)
+	croissantURL := fmt.Sprintf("%s/api/%s/%s/croissant", client.endpoint(), repoType+"s", repoID)
+	metadata, err := client.fetchMetadata(ctx, croissantURL)
 	if err == nil {
 		return metadata, nil
 	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
 
 	// Fall back to standard endpoint
-	standardURL := fmt.Sprintf("%s/api/%s/%s", baseURL, repoType+"s", repoID)
-	return client.fetchMetadata(standardURL)
+	standardURL := fmt.Sprintf("%s/api/%s/%s", client.endpoint(), repoType+"s", repoID)
+	return client.fetchMetadata(ctx, standardURL)
 }
 
-func (client *HuggingFaceClient) fetchMetadata(url string) (*MetadataResponse, error) {
-	req, err := http.NewRequest("GET", url, nil)
+func (client *HuggingFaceClient) fetchMetadata(ctx context.Context, url string) (*MetadataResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+client.APIKey)
+	if err := client.authorize(req); err != nil {
+		return nil, err
+	}
 
 	res, err := client.doRequest(req)
 	if err != nil {
@@ -0,0 +1,136 @@
+package apiv2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// TokenEnv holds a User Access Token, same as in huggingface_hub.
+	TokenEnv = "HF_TOKEN"
+	// TokenPathEnv overrides the location of the token file.
+	TokenPathEnv = "HF_TOKEN_PATH"
+)
+
+// TokenPath returns HF_TOKEN_PATH, defaulting to $HF_HOME/token, the file
+// written by `huggingface-cli login`.
+func TokenPath() string {
+	if path := os.Getenv(TokenPathEnv); path != "" {
+		return path
+	}
+	return filepath.Join(HFHome(), "token")
+}
+
+// ResolveToken returns token if it is set, then HF_TOKEN, then the content
+// of the token file. It returns "" when no token is found, for anonymous access.
+func ResolveToken(token string) string {
+	token, _ = DefaultTokenSource(token).Token(context.Background())
+	return token
+}
+
+// SaveToken writes token to the token file, readable by the user only.
+func SaveToken(token string) error {
+	path := TokenPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := writePrivateFile(path, []byte(token)); err != nil {
+		return fmt.Errorf("failed to save token: %w", err)
+	}
+	return nil
+}
+
+// writePrivateFile writes data to path, readable by the user only. The mode
+// of an existing file is tightened before data is written to it.
+func writePrivateFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// DeleteToken removes the token file. It is not an error if there is none.
+func DeleteToken() error {
+	err := os.Remove(TokenPath())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete token: %w", err)
+	}
+	return nil
+}
+
+// WhoAmIResponse is the account a token belongs to.
+type WhoAmIResponse struct {
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	FullName string `json:"fullname"`
+	Email    string `json:"email,omitempty"`
+	Orgs     []struct {
+		Name string `json:"name"`
+	} `json:"orgs"`
+	Auth struct {
+		AccessToken struct {
+			DisplayName string `json:"displayName"`
+			// Role is "read", "write" or "fineGrained".
+			Role string `json:"role"`
+		} `json:"accessToken"`
+	} `json:"auth"`
+}
+
+func (who *WhoAmIResponse) csvHeader() []string {
+	return []string{"name", "fullname", "type", "orgs", "tokenName", "tokenRole"}
+}
+
+func (who *WhoAmIResponse) csvRows() [][]string {
+	orgs := make([]string, len(who.Orgs))
+	for i, org := range who.Orgs {
+		orgs[i] = org.Name
+	}
+	return [][]string{{
+		who.Name, who.FullName, who.Type, strings.Join(orgs, ";"),
+		who.Auth.AccessToken.DisplayName, who.Auth.AccessToken.Role,
+	}}
+}
+
+func (client *HuggingFaceClient) WhoAmI() (*WhoAmIResponse, error) {
+	return client.WhoAmIContext(context.Background())
+}
+
+// WhoAmIContext returns the account of the client's token; ErrUnauthorized
+// means the token is missing or invalid.
+func (client *HuggingFaceClient) WhoAmIContext(ctx context.Context) (*WhoAmIResponse, error) {
+	url := fmt.Sprintf("%s/api/whoami-v2", client.endpoint())
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create whoami request: %w", err)
+	}
+	if err := client.authorize(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := client.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var who WhoAmIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&who); err != nil {
+		return nil, fmt.Errorf("failed to decode whoami response: %w", err)
+	}
+	return &who, nil
+}
@@ -0,0 +1,43 @@
+package apiv2
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestSaveToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "huggingface", "token")
+	t.Setenv(TokenPathEnv, path)
+	t.Setenv(TokenEnv, "")
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("hf_a_much_longer_old_token"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveToken("hf_new"); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || (runtime.GOOS != "windows" && info.Mode().Perm() != 0600) {
+		t.Errorf("token file mode = %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+	if got := ResolveToken(""); got != "hf_new" {
+		t.Errorf("ResolveToken after SaveToken = %q, want hf_new", got)
+	}
+	if got := ResolveToken("hf_flag"); got != "hf_flag" {
+		t.Errorf("ResolveToken(hf_flag) = %q, want the flag", got)
+	}
+
+	if err := DeleteToken(); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteToken(); err != nil {
+		t.Errorf("DeleteToken without a token file = %v, want nil", err)
+	}
+	if got := ResolveToken(""); got != "" {
+		t.Errorf("ResolveToken after DeleteToken = %q, want none", got)
+	}
+}
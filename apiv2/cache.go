@@ -0,0 +1,314 @@
+package apiv2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Environment variables shared with huggingface_hub to locate the cache.
+const (
+	HomeEnv     = "HF_HOME"
+	HubCacheEnv = "HF_HUB_CACHE"
+)
+
+var commitHashRegexp = regexp.MustCompile("^[0-9a-f]{40}$")
+
+// etagRegexp matches the ETags that are safe as the name of a blob.
+var etagRegexp = regexp.MustCompile(`^[0-9A-Za-z_-]+$`)
+
+// WithCacheDir stores downloaded files in dir instead of the default hub cache.
+func WithCacheDir(dir string) ClientOption {
+	return func(client *HuggingFaceClient) {
+		client.CacheDir = dir
+	}
+}
+
+// HFHome returns HF_HOME, defaulting to ~/.cache/huggingface like huggingface_hub.
+func HFHome() string {
+	if home := os.Getenv(HomeEnv); home != "" {
+		return home
+	}
+	if cache := os.Getenv("XDG_CACHE_HOME"); cache != "" {
+		return filepath.Join(cache, "huggingface")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "huggingface")
+	}
+	return filepath.Join(home, ".cache", "huggingface")
+}
+
+func (client *HuggingFaceClient) cacheDir() string {
+	if client.CacheDir != "" {
+		return client.CacheDir
+	}
+	if dir := os.Getenv(HubCacheEnv); dir != "" {
+		return dir
+	}
+	return filepath.Join(HFHome(), "hub")
+}
+
+// repoCacheDir is the folder of a repository in the cache, e.g. models--org--name.
+func (client *HuggingFaceClient) repoCacheDir(repoType, repoID string) string {
+	if repoType == "" {
+		repoType = "model"
+	}
+	parts := append([]string{repoType + "s"}, strings.Split(repoID, "/")...)
+	return filepath.Join(client.cacheDir(), strings.Join(parts, "--"))
+}
+
+// fileMetadata is what the Hub tells about a file without sending its content.
+type fileMetadata struct {
+	CommitHash string
+	ETag       string
+	Size       int64
+}
+
+// getFileMetadata sends a HEAD request to the resolve endpoint. Redirects to
+// the CDN are not followed since the headers we need are on the Hub's answer;
+// relative redirects (renamed repositories) are.
+func (client *HuggingFaceClient) getFileMetadata(ctx context.Context, repoType, repoID, revision, filePath string) (*fileMetadata, error) {
+	noRedirect := *client
+	httpClient := *client.httpClient()
+	httpClient.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	noRedirect.HTTPClient = &httpClient
+
+	url := client.resolveURL(repoType, repoID, revision, filePath)
+	for redirects := 0; ; redirects++ {
+		req, err := http.NewRequestWithContext(ctx, "HEAD", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create metadata request: %w", err)
+		}
+		if err := client.authorize(req); err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept-Encoding", "identity")
+
+		resp, err := noRedirect.doRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+
+		location := resp.Header.Get("Location")
+		if resp.StatusCode >= 300 && strings.HasPrefix(location, "/") && redirects < 5 {
+			url = client.endpoint() + location
+			continue
+		}
+
+		meta := &fileMetadata{
+			CommitHash: resp.Header.Get("X-Repo-Commit"),
+			ETag:       normalizeETag(firstHeader(resp.Header, "X-Linked-Etag", "ETag")),
+		}
+		meta.Size, _ = strconv.ParseInt(firstHeader(resp.Header, "X-Linked-Size", "Content-Length"), 10, 64)
+		if meta.CommitHash == "" || meta.ETag == "" {
+			return nil, fmt.Errorf("the Hub did not return a commit hash and ETag for %s", filePath)
+		}
+		return meta, nil
+	}
+}
+
+func firstHeader(header http.Header, keys ...string) string {
+	for _, key := range keys {
+		if value := header.Get(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// DownloadToCache downloads filePath into the local cache, laid out like
+// huggingface_hub's so that both tools share files, and returns its path in
+// the snapshot of the resolved commit. A file already in the cache is not
+// downloaded again; when the Hub cannot be reached the cached copy of the
+// last known commit of revision is used.
+func (client *HuggingFaceClient) DownloadToCache(repoType, repoID, revision, filePath string) (string, error) {
+	return client.DownloadToCacheContext(context.Background(), repoType, repoID, revision, filePath)
+}
+
+func (client *HuggingFaceClient) DownloadToCacheContext(ctx context.Context, repoType, repoID, revision, filePath string) (string, error) {
+	if revision == "" {
+		revision = DefaultRevision
+	}
+	// Both end up in paths of the cache, which they must not lead out of
+	if err := checkRepoPath(filePath); err != nil {
+		return "", err
+	}
+	if err := checkRevision(revision); err != nil {
+		return "", err
+	}
+	storage := client.repoCacheDir(repoType, repoID)
+	snapshotPath := func(commit string) string {
+		return filepath.Join(storage, "snapshots", commit, filepath.FromSlash(filePath))
+	}
+
+	// A commit hash never changes, no need to ask the Hub
+	if commitHashRegexp.MatchString(revision) {
+		if pointer := snapshotPath(revision); fileExists(pointer) {
+			return pointer, nil
+		}
+	}
+
+	meta, err := client.getFileMetadata(ctx, repoType, repoID, revision, filePath)
+	if err != nil {
+		var hubErr *HubError
+		if ctx.Err() == nil && !errors.As(err, &hubErr) {
+			// Offline: fall back to what the cache knows about revision
+			if commit, refErr := os.ReadFile(filepath.Join(storage, "refs", filepath.FromSlash(revision))); refErr == nil {
+				hash := strings.TrimSpace(string(commit))
+				if pointer := snapshotPath(hash); commitHashRegexp.MatchString(hash) && fileExists(pointer) {
+					return pointer, nil
+				}
+			}
+		}
+		return "", err
+	}
+
+	if !commitHashRegexp.MatchString(meta.CommitHash) || !etagRegexp.MatchString(meta.ETag) {
+		return "", fmt.Errorf("the Hub returned an invalid commit hash %q or ETag %q for %s", meta.CommitHash, meta.ETag, filePath)
+	}
+	if revision != meta.CommitHash {
+		if err := writeRef(storage, revision, meta.CommitHash); err != nil {
+			return "", err
+		}
+	}
+
+	pointer := snapshotPath(meta.CommitHash)
+	if fileExists(pointer) {
+		return pointer, nil
+	}
+
+	blob := filepath.Join(storage, "blobs", meta.ETag)
+	if err := os.MkdirAll(filepath.Dir(blob), 0755); err != nil {
+		return "", err
+	}
+	if !fileExists(blob) {
+		if err := client.downloadBlob(ctx, repoType, repoID, filePath, storage, blob, meta); err != nil {
+			return "", err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(pointer), 0755); err != nil {
+		return "", err
+	}
+	if err := linkBlob(blob, pointer); err != nil {
+		return "", err
+	}
+	return pointer, nil
+}
+
+// downloadBlob downloads filePath to blob while holding the lock of the
+// blob, the file .locks/<repo folder>/<etag>.lock of the cache that
+// huggingface_hub locks too, so that no other process writes to the same
+// .incomplete file at once.
+func (client *HuggingFaceClient) downloadBlob(ctx context.Context, repoType, repoID, filePath, storage, blob string, meta *fileMetadata) error {
+	lockPath := filepath.Join(client.cacheDir(), ".locks", filepath.Base(storage), meta.ETag+".lock")
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0755); err != nil {
+		return err
+	}
+	lock, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+	if err := lockFile(lock); err != nil {
+		return fmt.Errorf("failed to lock %s: %w", lockPath, err)
+	}
+
+	if fileExists(blob) {
+		// Downloaded by whoever held the lock before
+		return nil
+	}
+	want := etagChecksum(meta.ETag, meta.Size)
+	return client.downloadToPath(ctx, repoType, repoID, meta.CommitHash, filePath, blob, want)
+}
+
+// checkRevision rejects a revision that is absolute or goes up with "..",
+// which would lead out of the refs folder of the cache.
+func checkRevision(revision string) error {
+	if err := checkRepoPath(revision); err != nil {
+		return fmt.Errorf("invalid revision %s", revision)
+	}
+	return nil
+}
+
+func writeRef(storage, revision, commit string) error {
+	if err := checkRevision(revision); err != nil {
+		return err
+	}
+	ref := filepath.Join(storage, "refs", filepath.FromSlash(revision))
+	if current, err := os.ReadFile(ref); err == nil && string(current) == commit {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(ref), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(ref, []byte(commit), 0644)
+}
+
+// linkBlob points pointer at blob with a relative symlink, or copies blob
+// where symlinks are not available (e.g. Windows without developer mode).
+func linkBlob(blob, pointer string) error {
+	target, err := filepath.Rel(filepath.Dir(pointer), blob)
+	if err == nil {
+		if err = os.Symlink(target, pointer); err == nil || os.IsExist(err) {
+			return nil
+		}
+	}
+	return copyFile(blob, pointer)
+}
+
+// linkFile gives dst the content of the cached file src without another
+// copy of it on disk: a hard link to its blob, replacing dst if it exists.
+// It copies the blob where hard links are not possible, e.g. across file
+// systems. A file linked this way shares its content with the cache, so
+// editing it in place edits the cached blob too.
+func linkFile(src, dst string) error {
+	if blob, err := filepath.EvalSymlinks(src); err == nil {
+		tmp := dst + IncompleteSuffix
+		os.Remove(tmp)
+		if err := os.Link(blob, tmp); err == nil {
+			return os.Rename(tmp, dst)
+		}
+	}
+	return copyFile(src, dst)
+}
+
+// copyFile copies src to dst through dst+IncompleteSuffix, so that dst is
+// either complete or absent.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dst + IncompleteSuffix
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
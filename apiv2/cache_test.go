@@ -0,0 +1,123 @@
+package apiv2
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDownloadToCache(t *testing.T) {
+	content := []byte("cached content")
+	etag := "v1"
+	commit := strings.Repeat("c", 40)
+	server := newFileServer(t, content, etag)
+	cacheDir := t.TempDir()
+	client := NewHuggingFaceClient("", WithEndpoint(server.URL), WithCacheDir(cacheDir), WithRetryPolicy(NoRetry))
+
+	// Concurrent downloads of the same file share one blob
+	var wg sync.WaitGroup
+	pointers := make([]string, 4)
+	errs := make([]error, 4)
+	for i := range pointers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pointers[i], errs[i] = client.DownloadToCache("model", "user/repo", "main", "data.bin")
+		}()
+	}
+	wg.Wait()
+
+	storage := filepath.Join(cacheDir, "models--user--repo")
+	want := filepath.Join(storage, "snapshots", commit, "data.bin")
+	for i := range pointers {
+		if errs[i] != nil || pointers[i] != want {
+			t.Fatalf("DownloadToCache = %q, %v, want %q", pointers[i], errs[i], want)
+		}
+	}
+	if got, _ := os.ReadFile(want); !bytes.Equal(got, content) {
+		t.Errorf("snapshot file holds %q, want %q", got, content)
+	}
+	if got, _ := os.ReadFile(filepath.Join(storage, "blobs", etag)); !bytes.Equal(got, content) {
+		t.Errorf("blob holds %q, want %q", got, content)
+	}
+	if got, _ := os.ReadFile(filepath.Join(storage, "refs", "main")); string(got) != commit {
+		t.Errorf("refs/main = %q, want %s", got, commit)
+	}
+	if len(server.ranges) != 1 {
+		t.Errorf("the file was downloaded %d times, want once", len(server.ranges))
+	}
+
+	// A commit hash already in the cache needs no request at all
+	server.Close()
+	if pointer, err := client.DownloadToCache("model", "user/repo", commit, "data.bin"); err != nil || pointer != want {
+		t.Errorf("DownloadToCache of a cached commit = %q, %v, want %q", pointer, err, want)
+	}
+	// Offline, the cached commit of the branch is used
+	if pointer, err := client.DownloadToCache("model", "user/repo", "main", "data.bin"); err != nil || pointer != want {
+		t.Errorf("DownloadToCache offline = %q, %v, want %q", pointer, err, want)
+	}
+}
+
+func TestDownloadToCacheRejectsPaths(t *testing.T) {
+	server := newFileServer(t, []byte("content"), "v1")
+	client := NewHuggingFaceClient("", WithEndpoint(server.URL), WithCacheDir(t.TempDir()))
+	tests := []struct {
+		revision, filePath string
+	}{
+		{"main", "../../escape.bin"},
+		{"main", "/etc/passwd"},
+		{"../../refs", "data.bin"},
+		{"/abs", "data.bin"},
+	}
+	for _, tt := range tests {
+		if _, err := client.DownloadToCache("model", "user/repo", tt.revision, tt.filePath); err == nil {
+			t.Errorf("DownloadToCache(%q, %q) returned no error", tt.revision, tt.filePath)
+		}
+	}
+	if len(server.ranges) != 0 {
+		t.Errorf("%d downloads were made for rejected paths", len(server.ranges))
+	}
+}
+
+func TestLinkFile(t *testing.T) {
+	dir := t.TempDir()
+	blob := filepath.Join(dir, "blobs", "v1")
+	pointer := filepath.Join(dir, "snapshots", "c", "data.bin")
+	for _, path := range []string{blob, pointer} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(blob, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := linkBlob(blob, pointer); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := filepath.Join(dir, "local", "data.bin")
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// An existing file is replaced
+	if err := os.WriteFile(dest, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := linkFile(pointer, dest); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dest); string(got) != "content" {
+		t.Errorf("linked file holds %q, want content", got)
+	}
+	blobInfo, _ := os.Stat(blob)
+	destInfo, err := os.Lstat(dest)
+	if err != nil || destInfo.Mode()&os.ModeSymlink != 0 || !os.SameFile(blobInfo, destInfo) {
+		t.Errorf("%s is not a hard link to the blob", dest)
+	}
+	if fileExists(dest + IncompleteSuffix) {
+		t.Errorf("%s is left behind", dest+IncompleteSuffix)
+	}
+}
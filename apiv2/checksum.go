@@ -0,0 +1,75 @@
+package apiv2
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+)
+
+// checksum is what a file of a repository must hash to: the sha256 of LFS
+// files, the git blob SHA-1 of the others.
+type checksum struct {
+	algo string
+	sum  string
+	size int64
+}
+
+// fileChecksum returns the checksum of a tree entry, or nil if the Hub gave none.
+func fileChecksum(file HFFile) *checksum {
+	if file.LFS != nil {
+		return &checksum{algo: "sha256", sum: file.LFS.Oid, size: file.LFS.Size}
+	}
+	if file.Oid == "" {
+		return nil
+	}
+	return &checksum{algo: "sha1", sum: file.Oid, size: int64(file.Size)}
+}
+
+// etagChecksum returns the checksum carried by the ETag of the resolve
+// endpoint: the sha256 of LFS files, the git blob SHA-1 of the others.
+func etagChecksum(etag string, size int64) *checksum {
+	switch len(etag) {
+	case sha256.Size * 2:
+		return &checksum{algo: "sha256", sum: etag, size: size}
+	case sha1.Size * 2:
+		return &checksum{algo: "sha1", sum: etag, size: size}
+	}
+	return nil
+}
+
+func (c *checksum) newHash() hash.Hash {
+	if c.algo == "sha256" {
+		return sha256.New()
+	}
+	// git hashes a header before the content of a blob
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", c.size)
+	return h
+}
+
+// check returns a *ChecksumError if h does not sum to c.
+func (c *checksum) check(pathInRepo string, h hash.Hash) error {
+	if actual := hex.EncodeToString(h.Sum(nil)); actual != c.sum {
+		return &ChecksumError{Path: pathInRepo, Algorithm: c.algo, Expected: c.sum, Actual: actual}
+	}
+	return nil
+}
+
+// verifyFile checks the local file at path against c.
+func (c *checksum) verifyFile(pathInRepo, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := c.newHash()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	return c.check(pathInRepo, h)
+}
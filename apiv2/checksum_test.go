@@ -0,0 +1,102 @@
+package apiv2
+
+import (
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gitBlobSHA1 is the git blob hash of content, the ETag of a regular file.
+func gitBlobSHA1(content []byte) string {
+	c := &checksum{algo: "sha1", size: int64(len(content))}
+	h := c.newHash()
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func TestEtagChecksum(t *testing.T) {
+	sha256sum := strings.Repeat("ab", 32)
+	sha1sum := strings.Repeat("cd", 20)
+	tests := []struct {
+		etag     string
+		wantAlgo string
+	}{
+		{sha256sum, "sha256"},
+		{sha1sum, "sha1"},
+		{"", ""},
+		{"abc-123", ""},
+		{sha1sum + "-2", ""},
+	}
+	for _, tt := range tests {
+		got := etagChecksum(tt.etag, 42)
+		switch {
+		case got == nil && tt.wantAlgo != "":
+			t.Errorf("etagChecksum(%q) = nil, want %s", tt.etag, tt.wantAlgo)
+		case got != nil && (got.algo != tt.wantAlgo || got.sum != tt.etag || got.size != 42):
+			t.Errorf("etagChecksum(%q) = %+v, want %s of size 42", tt.etag, got, tt.wantAlgo)
+		}
+	}
+}
+
+func TestChecksumNewHash(t *testing.T) {
+	tests := []struct {
+		algo, content, want string
+	}{
+		// git hash-object of "hello\n"
+		{"sha1", "hello\n", "ce013625030ba8dba906f756967f9e9ca394464a"},
+		// git hash-object of an empty file
+		{"sha1", "", "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391"},
+		{"sha256", "hello\n", "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"},
+	}
+	for _, tt := range tests {
+		c := &checksum{algo: tt.algo, sum: tt.want, size: int64(len(tt.content))}
+		h := c.newHash()
+		h.Write([]byte(tt.content))
+		if got := hex.EncodeToString(h.Sum(nil)); got != tt.want {
+			t.Errorf("%s of %q = %s, want %s", tt.algo, tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestChecksumVerifyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hello.txt")
+	if err := os.WriteFile(path, []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	good := &checksum{algo: "sha1", sum: "ce013625030ba8dba906f756967f9e9ca394464a", size: 6}
+	if err := good.verifyFile("hello.txt", path); err != nil {
+		t.Errorf("verifyFile = %v, want nil", err)
+	}
+
+	bad := &checksum{algo: "sha1", sum: strings.Repeat("0", 40), size: 6}
+	err := bad.verifyFile("hello.txt", path)
+	var checksumErr *ChecksumError
+	if !errors.As(err, &checksumErr) || !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("verifyFile = %v, want a *ChecksumError", err)
+	}
+	if checksumErr.Expected != bad.sum || checksumErr.Actual != good.sum {
+		t.Errorf("ChecksumError = %+v, want Expected %s from the Hub and Actual %s", checksumErr, bad.sum, good.sum)
+	}
+}
+
+func TestDownloadToPathChecksumMismatch(t *testing.T) {
+	content := []byte("tampered content")
+	server := newFileServer(t, content, gitBlobSHA1([]byte("original content")))
+	dest := filepath.Join(t.TempDir(), "data.bin")
+
+	client := NewHuggingFaceClient("", WithEndpoint(server.URL))
+	err := client.DownloadToPath("model", "user/repo", "", "data.bin", dest)
+	var checksumErr *ChecksumError
+	if !errors.As(err, &checksumErr) {
+		t.Fatalf("DownloadToPath = %v, want a *ChecksumError", err)
+	}
+	if checksumErr.Expected != server.etag || checksumErr.Actual != gitBlobSHA1(content) {
+		t.Errorf("ChecksumError = %+v, want Expected from the Hub and Actual of the download", checksumErr)
+	}
+	if fileExists(dest) || fileExists(dest+IncompleteSuffix) {
+		t.Errorf("the corrupted download is left behind")
+	}
+}
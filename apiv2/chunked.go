@@ -0,0 +1,196 @@
+package apiv2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultChunkSize is the size of the byte ranges of a chunked download.
+const DefaultChunkSize = 64 << 20
+
+// errRangeIgnored stops a chunked download from a server that answers Range
+// requests with the whole file.
+var errRangeIgnored = errors.New("the server does not support Range requests")
+
+// WithChunkedDownload makes DownloadToPath split LFS files bigger than
+// chunkSize into byte ranges fetched over up to connections parallel
+// requests, the way hf_transfer does. chunkSize 0 means DefaultChunkSize.
+func WithChunkedDownload(connections int, chunkSize int64) ClientOption {
+	return func(client *HuggingFaceClient) {
+		client.ChunkConnections = connections
+		client.ChunkSize = chunkSize
+	}
+}
+
+func (client *HuggingFaceClient) chunkSize() int64 {
+	if client.ChunkSize <= 0 {
+		return DefaultChunkSize
+	}
+	return client.ChunkSize
+}
+
+// downloadChunkedToPath downloads filePath to dest in chunks if chunked
+// downloads are enabled and the file is a big enough LFS file. It reports
+// false when the caller should download the file in one piece instead.
+//
+// Chunks land anywhere in the .incomplete file, which is therefore removed
+// when the download fails rather than resumed by the next attempt.
+func (client *HuggingFaceClient) downloadChunkedToPath(ctx context.Context, repoType, repoName, revision, filePath, dest string, want *checksum) (bool, error) {
+	if client.ChunkConnections < 2 || want == nil || want.algo != "sha256" || want.size <= client.chunkSize() {
+		return false, nil
+	}
+
+	tmp := dest + IncompleteSuffix
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+	if err != nil {
+		return true, fmt.Errorf("failed to create %s: %w", tmp, err)
+	}
+	err = client.downloadChunked(ctx, repoType, repoName, revision, filePath, f, want)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		if errors.Is(err, errRangeIgnored) {
+			return false, nil
+		}
+		var checksumErr *ChecksumError
+		if errors.As(err, &checksumErr) {
+			return true, err
+		}
+		return true, fmt.Errorf("failed to download file: %w", err)
+	}
+	os.Remove(tmp + ETagSuffix)
+	return true, os.Rename(tmp, dest)
+}
+
+// downloadChunked fills f with filePath, fetching chunks concurrently and
+// writing each in place, then checks f against want.
+func (client *HuggingFaceClient) downloadChunked(ctx context.Context, repoType, repoName, revision, filePath string, f *os.File, want *checksum) error {
+	size := want.size
+	if err := f.Truncate(size); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var transferred int64
+	client.reportProgress(filePath, 0, size)
+	progress := func(int64) {
+		client.reportProgress(filePath, atomic.LoadInt64(&transferred), size)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	sem := make(chan struct{}, client.ChunkConnections)
+	for start := int64(0); start < size; start += client.chunkSize() {
+		end := start + client.chunkSize()
+		if end > size {
+			end = size
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			err := client.downloadChunk(ctx, repoType, repoName, revision, filePath, f, start, end, &transferred, progress)
+			if err != nil {
+				// One chunk gave up, the file cannot be completed
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	h := want.newHash()
+	if _, err := io.Copy(h, io.NewSectionReader(f, 0, size)); err != nil {
+		return err
+	}
+	return want.check(filePath, h)
+}
+
+// downloadChunk writes bytes [start, end) of filePath at the same offset of f.
+// A chunk is retried on its own, from where the failed attempt stopped.
+func (client *HuggingFaceClient) downloadChunk(ctx context.Context, repoType, repoName, revision, filePath string, f *os.File, start, end int64, transferred *int64, progress func(int64)) error {
+	policy := client.retryPolicy()
+	offset := start
+	for attempt := 1; ; attempt++ {
+		n, err := client.fetchRange(ctx, repoType, repoName, revision, filePath, f, offset, end, transferred, progress)
+		offset += n
+		if err == nil {
+			return nil
+		}
+
+		var hubErr *HubError
+		if ctx.Err() != nil || attempt >= policy.MaxAttempts || errors.As(err, &hubErr) || errors.Is(err, errRangeIgnored) {
+			return fmt.Errorf("chunk %d-%d: %w", start, end-1, err)
+		}
+		wait := policy.backoff(attempt, err)
+		if policy.OnRetry != nil {
+			policy.OnRetry(RetryEvent{
+				Method:  "GET",
+				URL:     client.resolveURL(repoType, repoName, revision, filePath),
+				Attempt: attempt,
+				Wait:    wait,
+				Err:     err,
+			})
+		}
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// fetchRange copies bytes [offset, end) of filePath to f and returns how many it wrote.
+func (client *HuggingFaceClient) fetchRange(ctx context.Context, repoType, repoName, revision, filePath string, f *os.File, offset, end int64, transferred *int64, progress func(int64)) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", client.resolveURL(repoType, repoName, revision, filePath), nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create download request: %w", err)
+	}
+	if err := client.authorize(req); err != nil {
+		return 0, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, end-1))
+
+	resp, err := client.doRequest(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return 0, errRangeIgnored
+	}
+
+	body := progressReader{r: io.LimitReader(resp.Body, end-offset), sent: transferred, add: progress}
+	n, err := io.Copy(io.NewOffsetWriter(f, offset), body)
+	if err == nil && n < end-offset {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
@@ -0,0 +1,73 @@
+package apiv2
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestDownloadChunked(t *testing.T) {
+	content := bytes.Repeat([]byte("abcdefghij"), 105)
+	sum := sha256.Sum256(content)
+	commit := strings.Repeat("c", 40)
+
+	tests := []struct {
+		name       string
+		chunkSize  int64
+		wantRanges []string
+	}{
+		{"uneven last chunk", 300, []string{"bytes=0-299", "bytes=300-599", "bytes=600-899", "bytes=900-1049"}},
+		{"exact chunks", 525, []string{"bytes=0-524", "bytes=525-1049"}},
+		// Files no bigger than a chunk are downloaded in one piece
+		{"single piece", 2000, []string{""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFileServer(t, content, hex.EncodeToString(sum[:]))
+			dest := filepath.Join(t.TempDir(), "data.bin")
+
+			client := NewHuggingFaceClient("", WithEndpoint(server.URL), WithChunkedDownload(3, tt.chunkSize))
+			if err := client.DownloadToPath("model", "user/repo", "main", "data.bin", dest); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := os.ReadFile(dest); !bytes.Equal(got, content) {
+				t.Errorf("chunks assembled into %d bytes that differ from the file", len(got))
+			}
+			if fileExists(dest + IncompleteSuffix) {
+				t.Errorf("%s is left behind", dest+IncompleteSuffix)
+			}
+
+			ranges := append([]string{}, server.ranges...)
+			sort.Strings(ranges)
+			if strings.Join(ranges, ",") != strings.Join(tt.wantRanges, ",") {
+				t.Errorf("Range headers = %q, want %q", ranges, tt.wantRanges)
+			}
+			// Every chunk comes from the commit the branch pointed to at the start
+			for _, path := range server.paths {
+				if !strings.Contains(path, "/resolve/"+commit+"/") {
+					t.Errorf("chunk requested from %s, want commit %s", path, commit)
+				}
+			}
+		})
+	}
+}
+
+func TestDownloadChunkedChecksumMismatch(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 1000)
+	server := newFileServer(t, content, strings.Repeat("0", 64))
+	dest := filepath.Join(t.TempDir(), "data.bin")
+
+	client := NewHuggingFaceClient("", WithEndpoint(server.URL), WithChunkedDownload(4, 100))
+	err := client.DownloadToPath("model", "user/repo", "", "data.bin", dest)
+	if _, ok := err.(*ChecksumError); !ok {
+		t.Errorf("DownloadToPath = %v, want a *ChecksumError", err)
+	}
+	if fileExists(dest) || fileExists(dest+IncompleteSuffix) {
+		t.Errorf("the corrupted download is left behind")
+	}
+}
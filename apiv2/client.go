@@ -0,0 +1,150 @@
+package apiv2
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultEndpoint is the Hub used when neither WithEndpoint nor HF_ENDPOINT is set.
+	DefaultEndpoint = "https://huggingface.co"
+	// DefaultDatasetsServerEndpoint serves dataset statistics.
+	DefaultDatasetsServerEndpoint = "https://datasets-server.huggingface.co"
+	// EndpointEnv overrides the Hub endpoint, same as in huggingface_hub.
+	EndpointEnv = "HF_ENDPOINT"
+	// DefaultRevision is used by every method given an empty revision.
+	DefaultRevision = "main"
+)
+
+// ClientOption configures a HuggingFaceClient created by NewHuggingFaceClient.
+type ClientOption func(*HuggingFaceClient)
+
+// WithEndpoint points the client at another Hub, e.g. a local mirror or an httptest server.
+func WithEndpoint(endpoint string) ClientOption {
+	return func(client *HuggingFaceClient) {
+		client.Endpoint = strings.TrimRight(endpoint, "/")
+	}
+}
+
+// WithDatasetsServerEndpoint overrides the datasets-server used for statistics.
+func WithDatasetsServerEndpoint(endpoint string) ClientOption {
+	return func(client *HuggingFaceClient) {
+		client.DatasetsServerEndpoint = strings.TrimRight(endpoint, "/")
+	}
+}
+
+// WithHTTPClient replaces the underlying HTTP client entirely. The client is
+// never modified: WithTransport, WithTimeout and WithProxy apply to a copy,
+// whatever the order of the options.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(client *HuggingFaceClient) {
+		client.HTTPClient = httpClient
+	}
+}
+
+// WithTransport sets the round tripper used for every request.
+func WithTransport(transport http.RoundTripper) ClientOption {
+	return func(client *HuggingFaceClient) {
+		client.httpOptions.transport = transport
+	}
+}
+
+// WithTimeout limits the total time of a single HTTP request, body included.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(client *HuggingFaceClient) {
+		client.httpOptions.timeout = &timeout
+	}
+}
+
+// WithProxy sends requests through the given proxy instead of the one
+// from HTTP_PROXY/HTTPS_PROXY. It has no effect on a custom transport
+// that is not an *http.Transport.
+func WithProxy(proxy *url.URL) ClientOption {
+	return func(client *HuggingFaceClient) {
+		client.httpOptions.proxy = proxy
+	}
+}
+
+// httpOptions are the settings of WithTransport, WithTimeout and WithProxy,
+// kept until every option has run.
+type httpOptions struct {
+	transport http.RoundTripper
+	timeout   *time.Duration
+	proxy     *url.URL
+}
+
+// applyHTTPOptions gives the client a copy of its HTTP client with the
+// settings of WithTransport, WithTimeout and WithProxy, so that neither
+// http.DefaultClient nor a client passed to WithHTTPClient is modified.
+func (client *HuggingFaceClient) applyHTTPOptions() {
+	opts := client.httpOptions
+	if opts.transport == nil && opts.timeout == nil && opts.proxy == nil {
+		return
+	}
+	httpClient := &http.Client{}
+	if client.HTTPClient != nil {
+		*httpClient = *client.HTTPClient
+	}
+	if opts.transport != nil {
+		httpClient.Transport = opts.transport
+	}
+	if opts.timeout != nil {
+		httpClient.Timeout = *opts.timeout
+	}
+	if opts.proxy != nil {
+		if httpClient.Transport == nil {
+			httpClient.Transport = http.DefaultTransport
+		}
+		if transport, ok := httpClient.Transport.(*http.Transport); ok {
+			transport = transport.Clone()
+			transport.Proxy = http.ProxyURL(opts.proxy)
+			httpClient.Transport = transport
+		}
+	}
+	client.HTTPClient = httpClient
+}
+
+func (client *HuggingFaceClient) httpClient() *http.Client {
+	if client.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return client.HTTPClient
+}
+
+func (client *HuggingFaceClient) endpoint() string {
+	if client.Endpoint != "" {
+		return strings.TrimRight(client.Endpoint, "/")
+	}
+	if env := os.Getenv(EndpointEnv); env != "" {
+		return strings.TrimRight(env, "/")
+	}
+	return DefaultEndpoint
+}
+
+// escapeRevision encodes a branch, tag or commit for use as one path segment,
+// so that "refs/pr/1" becomes "refs%2Fpr%2F1" as the Hub expects.
+func escapeRevision(revision string) string {
+	if revision == "" {
+		revision = DefaultRevision
+	}
+	return url.PathEscape(revision)
+}
+
+// escapeRepoPath encodes every segment of a path inside a repository.
+func escapeRepoPath(p string) string {
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func (client *HuggingFaceClient) datasetsServerEndpoint() string {
+	if client.DatasetsServerEndpoint != "" {
+		return strings.TrimRight(client.DatasetsServerEndpoint, "/")
+	}
+	return DefaultDatasetsServerEndpoint
+}
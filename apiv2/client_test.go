@@ -0,0 +1,58 @@
+package apiv2
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type countingTransport struct {
+	requests int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestClientRoundTrip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/datasets/user/repo/tree/main" {
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer hf_test" {
+			t.Errorf("Authorization = %q, want %q", got, "Bearer hf_test")
+		}
+		json.NewEncoder(w).Encode([]HFFile{{Type: "file", Path: "data.csv", Size: 3, Oid: "abc"}})
+	}))
+	defer server.Close()
+
+	shared := &http.Client{}
+	transport := &countingTransport{}
+	client := NewHuggingFaceClient("hf_test",
+		WithTimeout(time.Minute),
+		WithHTTPClient(shared),
+		WithTransport(transport),
+		WithEndpoint(server.URL),
+	)
+
+	files, err := client.ListFilesInRepo("dataset", "user/repo", "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Path != "data.csv" || files[0].Size != 3 {
+		t.Errorf("ListFilesInRepo = %+v, want data.csv of 3 bytes", files)
+	}
+	if transport.requests != 1 {
+		t.Errorf("transport sent %d requests, want 1", transport.requests)
+	}
+	if client.HTTPClient.Timeout != time.Minute {
+		t.Errorf("timeout = %v, want %v whatever the order of the options", client.HTTPClient.Timeout, time.Minute)
+	}
+	if shared.Transport != nil || shared.Timeout != 0 {
+		t.Errorf("the client given to WithHTTPClient was modified: %+v", shared)
+	}
+}
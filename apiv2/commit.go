@@ -0,0 +1,553 @@
+package apiv2
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// preuploadBatchSize is how many files are sent to the preupload endpoint at once.
+const preuploadBatchSize = 256
+
+// CommitInfo is the Hub's answer to a successful commit.
+type CommitInfo struct {
+	CommitURL      string `json:"commitUrl"`
+	CommitOid      string `json:"commitOid"`
+	PullRequestURL string `json:"pullRequestUrl,omitempty"`
+	// PullRequestRevision is refs/pr/N of the pull request opened by a
+	// commit with CreatePR, which later commits can target to add to it.
+	PullRequestRevision string `json:"pullRequestRevision,omitempty"`
+
+	// Ignored lists added files the repository's .gitignore made the Hub skip.
+	Ignored []string `json:"-"`
+}
+
+type commitOpKind int
+
+const (
+	opAdd commitOpKind = iota
+	opDelete
+	opDeleteFolder
+	opCopy
+)
+
+type commitOperation struct {
+	kind       commitOpKind
+	pathInRepo string
+
+	// opAdd: either content or localPath
+	content   []byte
+	localPath string
+
+	// opCopy
+	srcPath string
+
+	// filled in by Push
+	uploadMode string
+	ignored    bool
+}
+
+// withSource opens the content of an add operation for the duration of fn.
+func (op *commitOperation) withSource(fn func(uploadSource) error) error {
+	if op.localPath == "" {
+		return fn(uploadSource{r: bytes.NewReader(op.content), size: int64(len(op.content))})
+	}
+
+	f, err := os.Open(op.localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return fn(uploadSource{r: f, size: info.Size()})
+}
+
+// CommitBuilder gathers file operations and pushes them to the Hub as a single commit,
+// so that either all of them land in the repository or none does.
+type CommitBuilder struct {
+	client   *HuggingFaceClient
+	repoType string
+	repoID   string
+	revision string
+	ops      []*commitOperation
+
+	Summary     string
+	Description string
+	// CreatePR opens a pull request against the revision branch with the
+	// commit, instead of committing to the branch.
+	CreatePR bool
+
+	// Progress, when set, is called once an added file has been uploaded or read
+	// and is ready to be committed.
+	Progress func(pathInRepo string)
+}
+
+// NewCommit starts a commit on the revision branch of repoID with the given summary line.
+func (client *HuggingFaceClient) NewCommit(repoType, repoID, revision, summary string) *CommitBuilder {
+	if revision == "" {
+		revision = DefaultRevision
+	}
+	return &CommitBuilder{
+		client:   client,
+		repoType: repoType,
+		repoID:   repoID,
+		revision: revision,
+		Summary:  summary,
+	}
+}
+
+// AddFile adds or replaces pathInRepo with content.
+func (b *CommitBuilder) AddFile(pathInRepo string, content []byte) *CommitBuilder {
+	b.ops = append(b.ops, &commitOperation{kind: opAdd, pathInRepo: pathInRepo, content: content})
+	return b
+}
+
+// AddLocalFile adds or replaces pathInRepo with the file at localPath.
+// The file is read when the commit is pushed, never loaded into memory as a whole.
+func (b *CommitBuilder) AddLocalFile(pathInRepo, localPath string) *CommitBuilder {
+	b.ops = append(b.ops, &commitOperation{kind: opAdd, pathInRepo: pathInRepo, localPath: localPath})
+	return b
+}
+
+// DeleteFile removes pathInRepo.
+func (b *CommitBuilder) DeleteFile(pathInRepo string) *CommitBuilder {
+	b.ops = append(b.ops, &commitOperation{kind: opDelete, pathInRepo: pathInRepo})
+	return b
+}
+
+// DeleteFolder removes pathInRepo and everything below it.
+func (b *CommitBuilder) DeleteFolder(pathInRepo string) *CommitBuilder {
+	b.ops = append(b.ops, &commitOperation{kind: opDeleteFolder, pathInRepo: strings.TrimSuffix(pathInRepo, "/")})
+	return b
+}
+
+// CopyFile copies srcPath, as it is on the commit's branch now, to pathInRepo.
+// LFS files are copied by reference without transferring their content.
+func (b *CommitBuilder) CopyFile(srcPath, pathInRepo string) *CommitBuilder {
+	b.ops = append(b.ops, &commitOperation{kind: opCopy, pathInRepo: pathInRepo, srcPath: srcPath})
+	return b
+}
+
+// RepoPath returns the path in a repository of localPath, a file below
+// localRoot: its path relative to localRoot, in the folder pathInRepo of the
+// repository. Files outside of localRoot are rejected, as is a pathInRepo
+// that is absolute or leads out of the repository.
+func RepoPath(localRoot, localPath, pathInRepo string) (string, error) {
+	root, err := filepath.Abs(localRoot)
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(localPath)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside of the local root %s", localPath, localRoot)
+	}
+	if rel == "." {
+		return "", fmt.Errorf("%s is the local root itself, not a file below it", localPath)
+	}
+
+	if pathInRepo = strings.TrimRight(pathInRepo, "/"); pathInRepo != "" {
+		if err := checkRepoPath(pathInRepo); err != nil {
+			return "", err
+		}
+	}
+	return path.Join(pathInRepo, filepath.ToSlash(rel)), nil
+}
+
+// checkRepoPath rejects the paths that are absolute or go up with "..",
+// which would put a file out of the repository.
+func checkRepoPath(pathInRepo string) error {
+	if pathInRepo == "" {
+		return fmt.Errorf("empty path in repository")
+	}
+	if strings.HasPrefix(pathInRepo, "/") || strings.HasPrefix(pathInRepo, `\`) || filepath.IsAbs(pathInRepo) || filepath.VolumeName(pathInRepo) != "" {
+		return fmt.Errorf("invalid path in repository %s: absolute paths are not allowed", pathInRepo)
+	}
+	for _, part := range strings.FieldsFunc(pathInRepo, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if part == ".." {
+			return fmt.Errorf("invalid path in repository %s: it leads out of the repository", pathInRepo)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of operations gathered so far.
+func (b *CommitBuilder) Len() int {
+	return len(b.ops)
+}
+
+// Push uploads the content of added files and creates the commit.
+func (b *CommitBuilder) Push(ctx context.Context) (*CommitInfo, error) {
+	if len(b.ops) == 0 {
+		return nil, fmt.Errorf("nothing to commit")
+	}
+	for _, op := range b.ops {
+		if err := checkRepoPath(op.pathInRepo); err != nil {
+			return nil, err
+		}
+		if op.kind == opCopy {
+			if err := checkRepoPath(op.srcPath); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := b.preupload(ctx); err != nil {
+		return nil, err
+	}
+	copies, err := b.resolveCopies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	added, err := b.uploadAdded(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &CommitInfo{}
+	lines := []KeyValue{{
+		Key: "header",
+		Value: map[string]string{
+			"summary":     b.Summary,
+			"description": b.Description,
+		},
+	}}
+	for _, op := range b.ops {
+		if op.ignored {
+			info.Ignored = append(info.Ignored, op.pathInRepo)
+			continue
+		}
+
+		var line KeyValue
+		switch op.kind {
+		case opAdd:
+			line = added[op]
+		case opDelete:
+			line = KeyValue{Key: "deletedFile", Value: map[string]string{"path": op.pathInRepo}}
+		case opDeleteFolder:
+			line = KeyValue{Key: "deletedFolder", Value: map[string]string{"path": op.pathInRepo}}
+		case opCopy:
+			line = copies[op]
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 1 {
+		// Every added file was ignored by the Hub
+		return info, nil
+	}
+
+	url := fmt.Sprintf("%s/api/%s/%s/commit/%s", b.client.endpoint(), b.repoType+"s", b.repoID, escapeRevision(b.revision))
+	if b.CreatePR {
+		url += "?create_pr=1"
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, commitBody(lines))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create commit request: %w", err)
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		return commitBody(lines), nil
+	}
+	if err := b.client.authorize(req); err != nil {
+		req.Body.Close()
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := b.client.doRequestRetry(req, retryRejected)
+	if err != nil {
+		return nil, fmt.Errorf("commit failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(info); err != nil {
+		return nil, fmt.Errorf("failed to decode commit response: %w", err)
+	}
+	if num, ok := pullRequestNum(info.PullRequestURL); ok {
+		info.PullRequestRevision = pullRequestRevision(num)
+	}
+	return info, nil
+}
+
+// preupload asks the Hub for the upload mode of every added file.
+func (b *CommitBuilder) preupload(ctx context.Context) error {
+	var adds []*commitOperation
+	for _, op := range b.ops {
+		if op.kind == opAdd {
+			adds = append(adds, op)
+		}
+	}
+
+	for start := 0; start < len(adds); start += preuploadBatchSize {
+		end := start + preuploadBatchSize
+		if end > len(adds) {
+			end = len(adds)
+		}
+		batch := adds[start:end]
+
+		ufiles := UFiles{}
+		for _, op := range batch {
+			err := op.withSource(func(src uploadSource) error {
+				sample, err := src.sample()
+				if err != nil {
+					return err
+				}
+				ufiles.Files = append(ufiles.Files, UFile{
+					Path:   op.pathInRepo,
+					Sample: base64.StdEncoding.EncodeToString(sample),
+					Size:   src.size,
+				})
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", op.pathInRepo, err)
+			}
+		}
+
+		modes, err := b.client.preupload(ctx, b.repoType, b.repoID, b.revision, b.CreatePR, ufiles)
+		if err != nil {
+			return err
+		}
+		for _, op := range batch {
+			mode, ok := modes[op.pathInRepo]
+			if !ok {
+				return fmt.Errorf("pre-upload response has no entry for %s", op.pathInRepo)
+			}
+			op.uploadMode = mode.UploadMode
+			op.ignored = mode.ShouldIgnore
+		}
+	}
+	return nil
+}
+
+// uploadAdded uploads the added files, up to the client's Concurrency at
+// once, and returns their commit lines. A failed upload does not stop the
+// others, so that a new attempt only has to send what is missing.
+func (b *CommitBuilder) uploadAdded(ctx context.Context) (map[*commitOperation]KeyValue, error) {
+	var adds []*commitOperation
+	var paths []string
+	for _, op := range b.ops {
+		if op.kind == opAdd && !op.ignored {
+			adds = append(adds, op)
+			paths = append(paths, op.pathInRepo)
+		}
+	}
+
+	lines := make([]KeyValue, len(adds))
+	var mu sync.Mutex
+	err := forEachParallel(ctx, b.client.concurrency(), paths, func(i int, _ string) error {
+		line, err := b.addLine(ctx, adds[i])
+		if err != nil {
+			return err
+		}
+		lines[i] = line
+		if b.Progress != nil {
+			mu.Lock()
+			b.Progress(adds[i].pathInRepo)
+			mu.Unlock()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	added := make(map[*commitOperation]KeyValue, len(adds))
+	for i, op := range adds {
+		added[op] = lines[i]
+	}
+	return added, nil
+}
+
+// addLine uploads an added file to LFS if needed and returns its commit line.
+func (b *CommitBuilder) addLine(ctx context.Context, op *commitOperation) (KeyValue, error) {
+	var line KeyValue
+	err := op.withSource(func(src uploadSource) error {
+		var sent int64
+		src.progress = func(delta int64) {
+			b.client.reportProgress(op.pathInRepo, atomic.AddInt64(&sent, delta), src.size)
+		}
+
+		if op.uploadMode == "lfs" {
+			oid, err := b.client.uploadLFS(ctx, b.repoType, b.repoID, b.revision, src)
+			if err != nil {
+				return err
+			}
+			line = lfsFileLine(op.pathInRepo, oid)
+			return nil
+		}
+
+		// The content is read as the commit is sent, see commitBody
+		src.progress(src.size)
+		line = KeyValue{Key: "file", Value: inlineFile{op}}
+		return nil
+	})
+	return line, err
+}
+
+// inlineFile is the value of the commit line of a regular file, whose
+// content goes base64-encoded into the commit itself.
+type inlineFile struct {
+	op *commitOperation
+}
+
+// writeLine writes the commit line of the file to w, encoding its content
+// as it is read.
+func (file inlineFile) writeLine(w io.Writer) error {
+	path, _ := json.Marshal(file.op.pathInRepo)
+	if _, err := fmt.Fprintf(w, `{"key":"file","value":{"path":%s,"encoding":"base64","content":"`, path); err != nil {
+		return err
+	}
+	err := file.op.withSource(func(src uploadSource) error {
+		enc := base64.NewEncoder(base64.StdEncoding, w)
+		if _, err := io.Copy(enc, src.section(0, src.size)); err != nil {
+			return err
+		}
+		return enc.Close()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", file.op.pathInRepo, err)
+	}
+	_, err = io.WriteString(w, `"}}`)
+	return err
+}
+
+// commitBody streams lines as the NDJSON body of a commit, so that neither
+// the whole payload nor the content of the files is held in memory.
+func commitBody(lines []KeyValue) io.ReadCloser {
+	r, w := io.Pipe()
+	go func() {
+		bw := bufio.NewWriter(w)
+		var err error
+		for _, line := range lines {
+			if file, ok := line.Value.(inlineFile); ok {
+				err = file.writeLine(bw)
+			} else {
+				var data []byte
+				if data, err = json.Marshal(line); err == nil {
+					_, err = bw.Write(data)
+				}
+			}
+			if err == nil {
+				err = bw.WriteByte('\n')
+			}
+			if err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = bw.Flush()
+		}
+		w.CloseWithError(err)
+	}()
+	return r
+}
+
+func lfsFileLine(pathInRepo, oid string) KeyValue {
+	return KeyValue{
+		Key: "lfsFile",
+		Value: map[string]string{
+			"path": pathInRepo,
+			"algo": "sha256",
+			"oid":  oid,
+		},
+	}
+}
+
+// resolveCopies turns copy operations into commit lines: LFS files are
+// referenced by oid, regular files are downloaded and committed again.
+func (b *CommitBuilder) resolveCopies(ctx context.Context) (map[*commitOperation]KeyValue, error) {
+	var srcPaths []string
+	for _, op := range b.ops {
+		if op.kind == opCopy {
+			srcPaths = append(srcPaths, op.srcPath)
+		}
+	}
+	if len(srcPaths) == 0 {
+		return nil, nil
+	}
+
+	infos, err := b.client.pathsInfo(ctx, b.repoType, b.repoID, b.revision, srcPaths)
+	if err != nil {
+		return nil, err
+	}
+	byPath := make(map[string]HFFile, len(infos))
+	for _, info := range infos {
+		byPath[info.Path] = info
+	}
+
+	lines := make(map[*commitOperation]KeyValue)
+	for _, op := range b.ops {
+		if op.kind != opCopy {
+			continue
+		}
+		info, ok := byPath[op.srcPath]
+		if !ok || info.Type != "file" {
+			return nil, fmt.Errorf("cannot copy %s: no such file in %s", op.srcPath, b.repoID)
+		}
+		if info.LFS != nil {
+			lines[op] = lfsFileLine(op.pathInRepo, info.LFS.Oid)
+			continue
+		}
+
+		contents, err := b.client.DownloadFileContext(ctx, b.repoType, b.repoID, b.revision, op.srcPath)
+		if err != nil {
+			return nil, fmt.Errorf("cannot copy %s: %w", op.srcPath, err)
+		}
+		lines[op] = KeyValue{
+			Key: "file",
+			Value: map[string]string{
+				"content":  base64.StdEncoding.EncodeToString(contents),
+				"path":     op.pathInRepo,
+				"encoding": "base64",
+			},
+		}
+	}
+	return lines, nil
+}
+
+// pathsInfo returns the tree entries of the given paths.
+func (client *HuggingFaceClient) pathsInfo(ctx context.Context, repoType, repoID, revision string, paths []string) ([]HFFile, error) {
+	endpoint := fmt.Sprintf("%s/api/%s/%s/paths-info/%s", client.endpoint(), repoType+"s", repoID, escapeRevision(revision))
+	form := url.Values{"paths": paths}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create paths-info request: %w", err)
+	}
+	if err := client.authorize(req); err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.doRequestRetry(req, retryIdempotent)
+	if err != nil {
+		return nil, fmt.Errorf("paths-info request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var files []HFFile
+	if err := json.NewDecoder(resp.Body).Decode(&files); err != nil {
+		return nil, fmt.Errorf("failed to decode paths-info response: %w", err)
+	}
+	return files, nil
+}
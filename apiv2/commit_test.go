@@ -0,0 +1,175 @@
+package apiv2
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckRepoPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr bool
+	}{
+		{"model.safetensors", false},
+		{"data/train/0001.parquet", false},
+		{"weird..name", false},
+		{"..hidden/file", false},
+		{"", true},
+		{"/etc/passwd", true},
+		{`\share\file`, true},
+		{"../data", true},
+		{"data/../../x", true},
+		{`data\..\x`, true},
+		{"..", true},
+	}
+	for _, tt := range tests {
+		if err := checkRepoPath(tt.path); (err != nil) != tt.wantErr {
+			t.Errorf("checkRepoPath(%q) = %v, want error %v", tt.path, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRepoPath(t *testing.T) {
+	root := t.TempDir()
+	tests := []struct {
+		localPath, pathInRepo string
+		want                  string
+		wantErr               bool
+	}{
+		{filepath.Join(root, "model.bin"), "", "model.bin", false},
+		{filepath.Join(root, "data", "a.csv"), "", "data/a.csv", false},
+		{filepath.Join(root, "data", "a.csv"), "raw/", "raw/data/a.csv", false},
+		{filepath.Join(root, "a.csv"), "raw/2024", "raw/2024/a.csv", false},
+		{filepath.Join(root, "..", "outside.csv"), "", "", true},
+		{root, "", "", true},
+		{filepath.Join(root, "a.csv"), "../up", "", true},
+		{filepath.Join(root, "a.csv"), "/abs", "", true},
+	}
+	for _, tt := range tests {
+		got, err := RepoPath(root, tt.localPath, tt.pathInRepo)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("RepoPath(%q, %q) = %q, %v, want %q, error %v", tt.localPath, tt.pathInRepo, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCommitBody(t *testing.T) {
+	local := filepath.Join(t.TempDir(), "weights.bin")
+	if err := os.WriteFile(local, []byte("local content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lines := []KeyValue{
+		{Key: "header", Value: map[string]string{"summary": "Add files", "description": ""}},
+		{Key: "file", Value: inlineFile{&commitOperation{kind: opAdd, pathInRepo: `dir/"quoted".txt`, content: []byte("hello")}}},
+		{Key: "file", Value: inlineFile{&commitOperation{kind: opAdd, pathInRepo: "weights.bin", localPath: local}}},
+		lfsFileLine("big.bin", "abc123"),
+		{Key: "deletedFile", Value: map[string]string{"path": "old.txt"}},
+	}
+
+	// The body can be read more than once, for retries
+	for read := 0; read < 2; read++ {
+		body := commitBody(lines)
+		var got []string
+		scanner := bufio.NewScanner(body)
+		for scanner.Scan() {
+			var line struct {
+				Key   string            `json:"key"`
+				Value map[string]string `json:"value"`
+			}
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				t.Fatalf("line %q is not JSON: %v", scanner.Text(), err)
+			}
+			value := line.Value["path"]
+			if line.Key == "file" {
+				if line.Value["encoding"] != "base64" {
+					t.Errorf("encoding of %s = %q, want base64", value, line.Value["encoding"])
+				}
+				content, err := base64.StdEncoding.DecodeString(line.Value["content"])
+				if err != nil {
+					t.Fatal(err)
+				}
+				value += "=" + string(content)
+			}
+			got = append(got, line.Key+":"+value)
+		}
+		if err := scanner.Err(); err != nil {
+			t.Fatal(err)
+		}
+		body.Close()
+
+		want := []string{"header:", `file:dir/"quoted".txt=hello`, "file:weights.bin=local content", "lfsFile:big.bin", "deletedFile:old.txt"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("commit body = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestCommitBodyMissingFile(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.bin")
+	body := commitBody([]KeyValue{{Key: "file", Value: inlineFile{&commitOperation{kind: opAdd, pathInRepo: "missing.bin", localPath: missing}}}})
+	defer body.Close()
+	if _, err := io.ReadAll(body); err == nil || !strings.Contains(err.Error(), "missing.bin") {
+		t.Errorf("reading the body of a missing file = %v, want an error naming it", err)
+	}
+}
+
+func TestCommitPush(t *testing.T) {
+	var commits []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/api/models/user/repo/preupload/"):
+			var ufiles UFiles
+			json.NewDecoder(r.Body).Decode(&ufiles)
+			var response UFileResponse
+			for _, f := range ufiles.Files {
+				response.Files = append(response.Files, UFileMode{Path: f.Path, UploadMode: "regular"})
+			}
+			json.NewEncoder(w).Encode(response)
+		case r.URL.Path == "/api/models/user/repo/commit/main":
+			if got := r.Header.Get("Content-Type"); got != "application/x-ndjson" {
+				t.Errorf("Content-Type = %q, want application/x-ndjson", got)
+			}
+			body, _ := io.ReadAll(r.Body)
+			commits = append(commits, string(body))
+			if len(commits) == 1 {
+				// The Hub did not process the commit, which can be sent again
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			json.NewEncoder(w).Encode(CommitInfo{CommitURL: "https://hub/commit/1", CommitOid: "1234"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewHuggingFaceClient("hf_test", WithEndpoint(server.URL),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}))
+	info, err := client.NewCommit("model", "user/repo", "", "Update").
+		AddFile("a.txt", []byte("a")).
+		DeleteFile("b.txt").
+		Push(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.CommitOid != "1234" {
+		t.Errorf("CommitOid = %q, want 1234", info.CommitOid)
+	}
+	if len(commits) != 2 || commits[0] != commits[1] || commits[0] == "" {
+		t.Fatalf("commit bodies = %q, want the same body twice", commits)
+	}
+	if lines := strings.Split(strings.TrimSpace(commits[1]), "\n"); len(lines) != 3 {
+		t.Errorf("commit body has %d lines, want header, file and deletedFile: %q", len(lines), commits[1])
+	}
+}
@@ -0,0 +1,248 @@
+package apiv2
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ConfigPathEnv overrides the location of the config file.
+	ConfigPathEnv = "HUGGER_CONFIG"
+	// ProfileEnv selects a profile when -profile is not given.
+	ProfileEnv = "HUGGER_PROFILE"
+	// DefaultProfile is the profile used when none is selected.
+	DefaultProfile = "default"
+)
+
+// Colour themes of the tables printed by the command line tool.
+const (
+	ThemeDark  = "dark"
+	ThemeLight = "light"
+	ThemePlain = "plain"
+)
+
+// ProfileKeys are the settings of a profile, in the order config list shows them.
+var ProfileKeys = []string{"endpoint", "token", "repo-type", "concurrency", "output", "theme"}
+
+// Profile holds the defaults of the command line tool for one Hub account
+// or organisation. Empty fields keep the built-in defaults.
+type Profile struct {
+	Endpoint string `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
+	// Token refers to the token of the profile: "env:NAME" reads the
+	// environment variable NAME, "file:PATH" reads a file, and anything else
+	// is the token itself.
+	Token       string `yaml:"token,omitempty" json:"token,omitempty"`
+	RepoType    string `yaml:"repo-type,omitempty" json:"repo-type,omitempty"`
+	Concurrency int    `yaml:"concurrency,omitempty" json:"concurrency,omitempty"`
+	Output      string `yaml:"output,omitempty" json:"output,omitempty"`
+	Theme       string `yaml:"theme,omitempty" json:"theme,omitempty"`
+}
+
+// Config is the content of the config file: named profiles, and the one
+// used when none is selected.
+type Config struct {
+	DefaultProfile string              `yaml:"default-profile,omitempty"`
+	Profiles       map[string]*Profile `yaml:"profiles,omitempty"`
+}
+
+// ConfigPath returns HUGGER_CONFIG, defaulting to hugger/config.yaml in the
+// user's config directory, e.g. ~/.config/hugger/config.yaml.
+func ConfigPath() string {
+	if path := os.Getenv(ConfigPathEnv); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = filepath.Join(os.TempDir(), ".config")
+	}
+	return filepath.Join(dir, "hugger", "config.yaml")
+}
+
+// LoadConfig reads the config file at path. A missing file is an empty config.
+func LoadConfig(path string) (*Config, error) {
+	config := &Config{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for name, profile := range config.Profiles {
+		if profile == nil {
+			config.Profiles[name] = &Profile{}
+		}
+	}
+	return config, nil
+}
+
+// Save writes the config to path, readable by the user only since profiles
+// may hold tokens.
+func (config *Config) Save(path string) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(config); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := writePrivateFile(path, data); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	return nil
+}
+
+// ProfileName returns name, or else the default profile of the config.
+func (config *Config) ProfileName(name string) string {
+	if name != "" {
+		return name
+	}
+	if config.DefaultProfile != "" {
+		return config.DefaultProfile
+	}
+	return DefaultProfile
+}
+
+// Profile returns the profile called name, or the default one if name is
+// empty. Only a profile asked for by name has to exist.
+func (config *Config) Profile(name string) (*Profile, error) {
+	profile, ok := config.Profiles[config.ProfileName(name)]
+	if ok {
+		return profile, nil
+	}
+	if name != "" {
+		return nil, fmt.Errorf("profile %s not found in %s", name, ConfigPath())
+	}
+	return &Profile{}, nil
+}
+
+// SetProfile returns the profile called name, creating it if needed.
+func (config *Config) SetProfile(name string) *Profile {
+	name = config.ProfileName(name)
+	if config.Profiles == nil {
+		config.Profiles = make(map[string]*Profile)
+	}
+	if config.Profiles[name] == nil {
+		config.Profiles[name] = &Profile{}
+	}
+	return config.Profiles[name]
+}
+
+// ProfileNames returns the names of the profiles, sorted.
+func (config *Config) ProfileNames() []string {
+	names := make([]string, 0, len(config.Profiles))
+	for name := range config.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the setting key of the profile, one of ProfileKeys.
+func (p *Profile) Get(key string) (string, error) {
+	switch key {
+	case "endpoint":
+		return p.Endpoint, nil
+	case "token":
+		return p.Token, nil
+	case "repo-type":
+		return p.RepoType, nil
+	case "concurrency":
+		if p.Concurrency == 0 {
+			return "", nil
+		}
+		return strconv.Itoa(p.Concurrency), nil
+	case "output":
+		return p.Output, nil
+	case "theme":
+		return p.Theme, nil
+	}
+	return "", unknownKeyError(key)
+}
+
+// Set checks value and stores it as the setting key of the profile. An
+// empty value removes the setting.
+func (p *Profile) Set(key, value string) error {
+	switch key {
+	case "endpoint":
+		p.Endpoint = strings.TrimRight(value, "/")
+	case "token":
+		p.Token = value
+	case "repo-type":
+		switch value {
+		case "", "model", "dataset", "space":
+		default:
+			return fmt.Errorf("invalid repo-type %q, expected model, dataset or space", value)
+		}
+		p.RepoType = value
+	case "concurrency":
+		if value == "" {
+			p.Concurrency = 0
+			return nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid concurrency %q, expected a positive number", value)
+		}
+		p.Concurrency = n
+	case "output":
+		if _, err := IsMachineOutput(value); err != nil {
+			return err
+		}
+		p.Output = value
+	case "theme":
+		switch value {
+		case "", ThemeDark, ThemeLight, ThemePlain:
+		default:
+			return fmt.Errorf("invalid theme %q, expected dark, light or plain", value)
+		}
+		p.Theme = value
+	default:
+		return unknownKeyError(key)
+	}
+	return nil
+}
+
+func unknownKeyError(key string) error {
+	return fmt.Errorf("unknown setting %q, expected one of %s", key, strings.Join(ProfileKeys, ", "))
+}
+
+// TokenSource looks for a token in this order: token if it is set, HF_TOKEN,
+// the token of the profile, then the token file.
+func (p *Profile) TokenSource(token string) TokenSource {
+	var ref TokenSource = Anonymous
+	switch {
+	case strings.HasPrefix(p.Token, "env:"):
+		ref = EnvVarToken(strings.TrimPrefix(p.Token, "env:"))
+	case strings.HasPrefix(p.Token, "file:"):
+		ref = FileToken(strings.TrimPrefix(p.Token, "file:"))
+	case p.Token != "":
+		ref = StaticToken(p.Token)
+	}
+	return ChainTokenSource(StaticToken(token), EnvToken, ref, FileToken(TokenPath()))
+}
+
+// redactedToken shows the token setting without the token itself.
+func (p *Profile) redactedToken() string {
+	if strings.HasPrefix(p.Token, "env:") || strings.HasPrefix(p.Token, "file:") {
+		return p.Token
+	}
+	if p.Token == "" {
+		return ""
+	}
+	return "***"
+}
@@ -0,0 +1,131 @@
+package apiv2
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestProfileSetGet(t *testing.T) {
+	tests := []struct {
+		key, value string
+		want       string
+		wantErr    bool
+	}{
+		{"endpoint", "https://hub.example/", "https://hub.example", false},
+		{"token", "env:WORK_TOKEN", "env:WORK_TOKEN", false},
+		{"repo-type", "dataset", "dataset", false},
+		{"repo-type", "image", "", true},
+		{"concurrency", "8", "8", false},
+		{"concurrency", "0", "", true},
+		{"concurrency", "many", "", true},
+		{"concurrency", "", "", false},
+		{"output", "json", "json", false},
+		{"output", "xml", "", true},
+		{"theme", "light", "light", false},
+		{"theme", "neon", "", true},
+		{"colour", "red", "", true},
+	}
+	for _, tt := range tests {
+		p := &Profile{}
+		err := p.Set(tt.key, tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Set(%q, %q) = %v, want error %v", tt.key, tt.value, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got, err := p.Get(tt.key); err != nil || got != tt.want {
+			t.Errorf("Get(%q) after Set(%q) = %q, %v, want %q", tt.key, tt.value, got, err, tt.want)
+		}
+	}
+	if _, err := (&Profile{}).Get("colour"); err == nil {
+		t.Errorf("Get of an unknown setting returned no error")
+	}
+}
+
+func TestProfileTokenSource(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("hf_file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	profileFile := filepath.Join(t.TempDir(), "profile-token")
+	if err := os.WriteFile(profileFile, []byte("hf_profile_file"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(TokenPathEnv, tokenFile)
+	t.Setenv("HUGGER_TEST_TOKEN", "hf_profile_env")
+
+	tests := []struct {
+		flag, env, profile string
+		want               string
+	}{
+		{"hf_flag", "hf_env", "hf_profile", "hf_flag"},
+		{"", "hf_env", "hf_profile", "hf_env"},
+		{"", "", "hf_profile", "hf_profile"},
+		{"", "", "env:HUGGER_TEST_TOKEN", "hf_profile_env"},
+		{"", "", "file:" + profileFile, "hf_profile_file"},
+		{"", "", "", "hf_file"},
+	}
+	for _, tt := range tests {
+		t.Setenv(TokenEnv, tt.env)
+		p := &Profile{Token: tt.profile}
+		got, err := p.TokenSource(tt.flag).Token(context.Background())
+		if err != nil || got != tt.want {
+			t.Errorf("TokenSource(%q) with HF_TOKEN %q and profile token %q = %q, %v, want %q", tt.flag, tt.env, tt.profile, got, err, tt.want)
+		}
+	}
+}
+
+func TestProfileRedactedToken(t *testing.T) {
+	tests := []struct {
+		token, want string
+	}{
+		{"", ""},
+		{"hf_secret", "***"},
+		{"env:WORK_TOKEN", "env:WORK_TOKEN"},
+		{"file:/run/secrets/hf", "file:/run/secrets/hf"},
+	}
+	for _, tt := range tests {
+		if got := (&Profile{Token: tt.token}).redactedToken(); got != tt.want {
+			t.Errorf("redactedToken of %q = %q, want %q", tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestConfigSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hugger", "config.yaml")
+	// An existing file readable by others is tightened
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &Config{DefaultProfile: "work"}
+	if err := config.SetProfile("").Set("token", "hf_secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || (runtime.GOOS != "windows" && info.Mode().Perm() != 0600) {
+		t.Errorf("config file mode = %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+
+	loaded, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile, err := loaded.Profile("")
+	if err != nil || profile.Token != "hf_secret" || loaded.ProfileName("") != "work" {
+		t.Errorf("loaded config = %+v, %+v, %v", loaded, profile, err)
+	}
+	if _, err := loaded.Profile("missing"); err == nil {
+		t.Errorf("Profile of a missing name returned no error")
+	}
+}
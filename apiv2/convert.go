@@ -1,6 +1,7 @@
 package apiv2
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -8,14 +9,22 @@ import (
 
 // Convert2 retrieves data in a specified format from a HuggingFace API endpoint.
 func (client *HuggingFaceClient) Convert2(format, what string) ([]byte, error) {
+	return client.Convert2Context(context.Background(), format, what)
+}
+
+// Convert2Context is Convert2 with a caller-supplied context.
+func (client *HuggingFaceClient) Convert2Context(ctx context.Context, format, what string) ([]byte, error) {
 	// Construct the URL using base URL, the type (what), and format.
-	url := fmt.Sprintf("%s/api/%s/%s", baseURL, what, format)
+	url := fmt.Sprintf("%s/api/%s/%s", client.endpoint(), what, format)
 
 	// Create a new HTTP GET request.
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	if err := client.authorize(req); err != nil {
+		return nil, err
+	}
 
 	// Execute the request using the client's configured doRequest method.
 	res, err := client.doRequest(req)
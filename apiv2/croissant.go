@@ -1,14 +1,16 @@
 package apiv2
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/jedib0t/go-pretty/v6/progress"
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/jedib0t/go-pretty/v6/text"
-	"github.com/k0kubun/go-ansi"
-	progressbar "github.com/schollz/progressbar/v3"
 )
 
 func prepareDescription( description string ) string {
@@ -48,65 +50,688 @@ func prepareDescription( description string ) string {
 	return res
 }
 
+// Request is a single subcommand of the command line tool.
+type Request struct {
+	Type     string // meta, statistics, download, snapshot, verify, upload, repo, repo-files, sync, refs, log, discussions, login, logout, whoami or config
+	RepoID   string
+	RepoType string
+	// Revision is the branch, tag or commit to work on; empty means DefaultRevision.
+	Revision string
+	// Token is resolved with ResolveToken; login saves it to the token file.
+	Token    string
+	Action   string
+	Split    string
+	Files    []string
+	Private  bool
+	// NoCache downloads files straight to their destination, bypassing the local cache.
+	NoCache bool
+
+	// CommitMessage and CommitDescription describe the commit made by upload,
+	// sync push and repo-files -action delete.
+	CommitMessage     string
+	CommitDescription string
+
+	// Include and Exclude are the glob patterns of snapshot and upload,
+	// LocalDir the destination of snapshot, the folder checked by verify and
+	// the one sync works on.
+	Include  []string
+	Exclude  []string
+	LocalDir string
+	// LocalRoot is the folder that upload makes Files relative to, the
+	// current one if empty, and PathInRepo the folder of the repository
+	// they go to.
+	LocalRoot  string
+	PathInRepo string
+
+	// Concurrency is how many files download, snapshot and upload transfer at once.
+	Concurrency int
+	// Connections above 1 downloads big LFS files in that many parallel chunks.
+	Connections int
+
+	// Output is the format of the results: OutputTable (the default), OutputJSON,
+	// OutputYAML or OutputCSV.
+	Output string
+	// Visibility ("public" or "private") and Gated (GatedAuto, GatedManual
+	// or GatedOff) are the changes of repo -action settings, ToRepoID the new
+	// name of repo -action move.
+	Visibility string
+	Gated      string
+	ToRepoID   string
+	// DryRun only shows what a change would do.
+	DryRun bool
+	// Delete makes sync remove the files missing from its source, once
+	// Confirm agreed to the list or Yes is set.
+	Delete  bool
+	Yes     bool
+	Confirm func(question string) bool
+
+	// Ref is the branch or tag that refs creates or deletes, Message the
+	// message of an annotated tag, the description of a new discussion or
+	// the comment that discussions posts.
+	Ref     string
+	Message string
+
+	// Commit is the commit that log show shows. Path keeps the
+	// commits of log that touched it, Limit stops log after that many commits.
+	Commit string
+	Path   string
+	Limit  int
+
+	// Num is the discussion or pull request that discussions works on, Title
+	// the title of a new one. Status, DiscussionType and Author filter
+	// discussions list.
+	Num            int
+	Title          string
+	Status         string
+	DiscussionType string
+	Author         string
+	// CreatePR makes upload and sync push open a pull request instead of
+	// committing to Revision, and discussions open a pull request rather
+	// than a discussion.
+	CreatePR bool
+
+	// Theme is the colour theme of the tables: ThemeDark (the default),
+	// ThemeLight or ThemePlain, which has no colour at all.
+	Theme string
+
+	// Profile, Key and Value are the profile and the setting that config
+	// get and set work on.
+	Profile string
+	Key     string
+	Value   string
+}
+
 func ServeRequest(reqType, repoName, repoType, token, action, split string, files []string, private bool) error {
-	client := HuggingFaceClient{Token: token}
+	return ServeRequestContext(context.Background(), reqType, repoName, repoType, token, action, split, files, private)
+}
 
-	switch reqType {
+// ServeRequestContext is ServeRequest that stops as soon as ctx is cancelled,
+// e.g. when the user presses Ctrl-C in the middle of a transfer.
+// opts are applied to the client that serves the request.
+func ServeRequestContext(ctx context.Context, reqType, repoName, repoType, token, action, split string, files []string, private bool, opts ...ClientOption) error {
+	return Serve(ctx, Request{
+		Type:     reqType,
+		RepoID:   repoName,
+		RepoType: repoType,
+		Token:    token,
+		Action:   action,
+		Split:    split,
+		Files:    files,
+		Private:  private,
+	}, opts...)
+}
+
+// Serve runs r with a client configured by opts.
+func Serve(ctx context.Context, r Request, opts ...ClientOption) error {
+	client := HuggingFaceClient{TokenSource: DefaultTokenSource(r.Token)}
+	for _, opt := range opts {
+		opt(&client)
+	}
+	client.applyHTTPOptions()
+	if r.Concurrency > 0 {
+		client.Concurrency = r.Concurrency
+	}
+	if r.Connections > 1 {
+		WithChunkedDownload(r.Connections, 0)(&client)
+	}
+	repoName, repoType, files := r.RepoID, r.RepoType, r.Files
+	machine, err := IsMachineOutput(r.Output)
+	if err != nil {
+		return err
+	}
+
+	switch r.Type {
 	case "meta":
-		meta, err := client.GetMetadata(repoType, repoName)
+		meta, err := client.GetMetadataContext(ctx, repoType, repoName, r.Revision)
 		if err != nil {
 			return err
 		}
-		displayMetadata(meta)
+		if machine {
+			return writeOutput(os.Stdout, r.Output, meta)
+		}
+		displayMetadata(meta, r.Theme)
 
 	case "statistics":
-		stat, err := client.GetDatasetStatistics( repoName, split )
+		stat, err := client.GetDatasetStatisticsContext( ctx, repoName, r.Split )
 		if err != nil {
-			return fmt.Errorf("failed to get statistics for %s: %s", repoName, err)
+			return fmt.Errorf("failed to get statistics for %s: %w", repoName, err)
+		}
+		if machine {
+			return writeOutput(os.Stdout, r.Output, stat)
 		}
-		displayStatistics(stat, repoName)
+		displayStatistics(stat, repoName, r.Theme)
 
 	case "download":
-		if err := processFiles(client, files, repoType, repoName, "download"); err != nil {
+		if err := processFiles(ctx, client, files, repoType, repoName, r.Revision, "download", r.NoCache, r.Output); err != nil {
+			return err
+		}
+
+	case "snapshot":
+		if err := downloadSnapshot(ctx, client, r); err != nil {
+			return err
+		}
+
+	case "verify":
+		if err := verifyLocalDir(ctx, client, r); err != nil {
 			return err
 		}
 
 	case "upload":
-		if err := processFiles(client, files, repoType, repoName, "upload"); err != nil {
+		if err := uploadFiles(ctx, client, r); err != nil {
+			return err
+		}
+
+	case "sync":
+		if err := syncFolder(ctx, client, r); err != nil {
 			return err
 		}
 
 	case "repo":
-		if err := manageRepo(client, repoType, repoName, action, private); err != nil {
+		if err := manageRepo(ctx, client, r); err != nil {
 			return err
 		}
 
 	case "repo-files":
-		if err := manageRepoFiles(client, repoType, repoName, files, action); err != nil {
+		if err := manageRepoFiles(ctx, client, r); err != nil {
+			return err
+		}
+
+	case "refs":
+		if err := manageRefs(ctx, client, r); err != nil {
+			return err
+		}
+
+	case "log":
+		if err := showLog(ctx, client, r); err != nil {
+			return err
+		}
+
+	case "discussions":
+		if err := manageDiscussions(ctx, client, r); err != nil {
+			return err
+		}
+
+	case "whoami":
+		who, err := client.WhoAmIContext(ctx)
+		if err != nil {
+			return err
+		}
+		if machine {
+			return writeOutput(os.Stdout, r.Output, who)
+		}
+		displayWhoAmI(who, r.Theme)
+
+	case "login":
+		return login(ctx, client, r)
+
+	case "config":
+		return manageConfig(r)
+
+	case "logout":
+		if err := DeleteToken(); err != nil {
+			return err
+		}
+		fmt.Printf("👋 Logged out, %s removed\n", TokenPath())
+		if os.Getenv(TokenEnv) != "" {
+			fmt.Printf("⚠️  %s is still set and will be used\n", TokenEnv)
+		}
+
+	default:
+		return fmt.Errorf("invalid command: %s", r.Type)
+	}
+	return nil
+}
+
+// login checks the token of r with the Hub before saving it to the token file.
+func login(ctx context.Context, client HuggingFaceClient, r Request) error {
+	if r.Token == "" {
+		return fmt.Errorf("no token given")
+	}
+	who, err := client.WhoAmIContext(ctx)
+	if err != nil {
+		return fmt.Errorf("invalid token: %w", err)
+	}
+	if err := SaveToken(r.Token); err != nil {
+		return err
+	}
+	if machine, _ := IsMachineOutput(r.Output); machine {
+		return writeOutput(os.Stdout, r.Output, who)
+	}
+	fmt.Printf("🔑 Logged in as %s, token saved to %s\n", who.Name, TokenPath())
+	if os.Getenv(TokenEnv) != "" {
+		fmt.Printf("⚠️  %s is set and takes precedence over the saved token\n", TokenEnv)
+	}
+	return nil
+}
+
+// refResult is the machine-readable output of the refs actions that change a ref.
+type refResult struct {
+	Action   string `json:"action"`
+	Ref      string `json:"ref"`
+	Revision string `json:"revision,omitempty"`
+}
+
+func (r *refResult) csvHeader() []string {
+	return []string{"action", "ref", "revision"}
+}
+
+func (r *refResult) csvRows() [][]string {
+	return [][]string{{r.Action, r.Ref, r.Revision}}
+}
+
+func manageRefs(ctx context.Context, client HuggingFaceClient, r Request) error {
+	machine, _ := IsMachineOutput(r.Output)
+	if r.Action != "list" && r.Ref == "" {
+		return fmt.Errorf("refs -action %s requires the name of the branch or tag", r.Action)
+	}
+
+	var err error
+	switch r.Action {
+	case "list":
+		refs, err := client.ListRefsContext(ctx, r.RepoType, r.RepoID)
+		if err != nil {
+			return fmt.Errorf("failed to list refs: %w", err)
+		}
+		if machine {
+			return writeOutput(os.Stdout, r.Output, refs)
+		}
+
+		tw := table.NewWriter()
+		tw.AppendHeader( table.Row{ "Refs of " + r.RepoID, "Type", "Commit" } )
+		for _, ref := range refs.Branches {
+			tw.AppendRow( table.Row{ paint( r.Theme, 0, 200, 200, ref.Name ), "branch", ref.TargetCommit } )
+		}
+		for _, ref := range refs.Tags {
+			tw.AppendRow( table.Row{ ref.Name, "tag", ref.TargetCommit } )
+		}
+		tw.AppendFooter( table.Row{ "Total", "", fmt.Sprintf("%d branches, %d tags", len(refs.Branches), len(refs.Tags)) } )
+		fmt.Println(renderTable( tw, r.Theme, text.BgBlue ))
+		return nil
+
+	case "create-branch":
+		err = client.CreateBranchContext(ctx, r.RepoType, r.RepoID, r.Ref, r.Revision)
+	case "delete-branch":
+		err = client.DeleteBranchContext(ctx, r.RepoType, r.RepoID, r.Ref)
+	case "create-tag":
+		err = client.CreateTagContext(ctx, r.RepoType, r.RepoID, r.Ref, r.Revision, r.Message)
+	case "delete-tag":
+		err = client.DeleteTagContext(ctx, r.RepoType, r.RepoID, r.Ref)
+	default:
+		return fmt.Errorf("invalid refs action: %s", r.Action)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to %s %s: %w", strings.Replace(r.Action, "-", " ", 1), r.Ref, err)
+	}
+
+	revision := ""
+	if strings.HasPrefix(r.Action, "create") {
+		revision = r.Revision
+		if revision == "" {
+			revision = DefaultRevision
+		}
+	}
+	if machine {
+		return writeOutput(os.Stdout, r.Output, &refResult{r.Action, r.Ref, revision})
+	}
+	switch r.Action {
+	case "create-branch":
+		fmt.Printf("🌿 Branch %s created from %s\n", r.Ref, revision)
+	case "delete-branch":
+		fmt.Printf("🗑️  Branch %s deleted\n", r.Ref)
+	case "create-tag":
+		fmt.Printf("🏷️  Tag %s created on %s\n", r.Ref, revision)
+	case "delete-tag":
+		fmt.Printf("🗑️  Tag %s deleted\n", r.Ref)
+	}
+	return nil
+}
+
+func showLog(ctx context.Context, client HuggingFaceClient, r Request) error {
+	machine, _ := IsMachineOutput(r.Output)
+	switch r.Action {
+	case "list":
+		commits, err := client.ListCommitsContext(ctx, r.RepoType, r.RepoID, r.Revision, CommitListOptions{Path: r.Path, Limit: r.Limit})
+		if err != nil {
+			return fmt.Errorf("failed to list commits: %w", err)
+		}
+		if machine {
+			return writeOutput(os.Stdout, r.Output, commitListing(commits))
+		}
+
+		title := "History of " + r.RepoID
+		if r.Path != "" {
+			title += ":" + r.Path
+		}
+		tw := table.NewWriter()
+		tw.AppendHeader( table.Row{ title, "Date", "Authors", "Title" } )
+		for _, c := range commits {
+			tw.AppendRow( table.Row{ paint( r.Theme, 0, 200, 200, shortCommit( c.ID ) ), c.Date.Local().Format( "2006-01-02 15:04" ), c.AuthorNames(), c.Title } )
+		}
+		tw.AppendFooter( table.Row{ "Total", "", "", fmt.Sprintf("%d commits", len(commits)) } )
+		fmt.Println(renderTable( tw, r.Theme, text.BgBlue ))
+
+	case "show":
+		if r.Commit == "" {
+			return fmt.Errorf("log show requires a commit: log show <commit>")
+		}
+		commit, err := client.GetCommitContext(ctx, r.RepoType, r.RepoID, r.Commit)
+		if err != nil {
+			return fmt.Errorf("failed to get commit %s: %w", r.Commit, err)
+		}
+		if machine {
+			return writeOutput(os.Stdout, r.Output, commit)
+		}
+
+		tw := table.NewWriter()
+		tw.AppendHeader( table.Row{ "Commit", commit.Title } )
+		tw.AppendRow( table.Row{ "ID", paint( r.Theme, 0, 200, 200, commit.ID ) } )
+		tw.AppendRow( table.Row{ "Authors", commit.AuthorNames() } )
+		tw.AppendRow( table.Row{ "Date", commit.Date.Local().Format( "2006-01-02 15:04:05" ) } )
+		if message := strings.TrimSpace( strings.TrimPrefix( commit.Message, commit.Title ) ); message != "" {
+			tw.AppendRow( table.Row{ "Message", message } )
+		}
+		tw.AppendSeparator()
+		for _, f := range commit.Files {
+			path := f.Path
+			if f.OldPath != "" {
+				path = f.OldPath + " → " + f.Path
+			}
+			tw.AppendRow( table.Row{ f.Status, path } )
+		}
+		tw.AppendFooter( table.Row{ "Files", fmt.Sprintf("%d", len(commit.Files)) } )
+		fmt.Println(renderTable( tw, r.Theme, text.BgBlue ))
+
+	default:
+		return fmt.Errorf("invalid log action: %s", r.Action)
+	}
+	return nil
+}
+
+// shortCommit abbreviates a commit id the way git does.
+func shortCommit( id string ) string {
+	if len( id ) > 7 {
+		return id[:7]
+	}
+	return id
+}
+
+// discussionResult is the machine-readable output of the discussions actions
+// that change a discussion.
+type discussionResult struct {
+	Action   string `json:"action"`
+	Num      int    `json:"num"`
+	Status   string `json:"status,omitempty"`
+	Revision string `json:"revision,omitempty"`
+	URL      string `json:"url"`
+}
+
+func (r *discussionResult) csvHeader() []string {
+	return []string{"action", "num", "status", "revision", "url"}
+}
+
+func (r *discussionResult) csvRows() [][]string {
+	return [][]string{{r.Action, fmt.Sprintf("%d", r.Num), r.Status, r.Revision, r.URL}}
+}
+
+func manageDiscussions(ctx context.Context, client HuggingFaceClient, r Request) error {
+	machine, _ := IsMachineOutput(r.Output)
+	if r.Action != "list" && r.Num <= 0 && !(r.Action == "open" && r.Title != "") {
+		return fmt.Errorf("discussions %s requires the number of a discussion", r.Action)
+	}
+
+	result := &discussionResult{Action: r.Action, Num: r.Num, URL: client.DiscussionURL(r.RepoType, r.RepoID, r.Num)}
+	var err error
+	switch r.Action {
+	case "list":
+		discussions, err := client.ListDiscussionsContext(ctx, r.RepoType, r.RepoID, DiscussionListOptions{Status: r.Status, Type: r.DiscussionType, Author: r.Author})
+		if err != nil {
+			return fmt.Errorf("failed to list discussions: %w", err)
+		}
+		if machine {
+			return writeOutput(os.Stdout, r.Output, discussionListing(discussions))
+		}
+
+		tw := table.NewWriter()
+		tw.AppendHeader( table.Row{ "#", "Discussions of " + r.RepoID, "Status", "Author", "Created" } )
+		for _, d := range discussions {
+			title := d.Title
+			if d.IsPullRequest {
+				title = paint( r.Theme, 0, 200, 200, "[PR]" ) + " " + title
+			}
+			tw.AppendRow( table.Row{ d.Num, title, d.Status, d.Author.Name, d.CreatedAt.Local().Format( "2006-01-02" ) } )
+		}
+		tw.AppendFooter( table.Row{ "", "Total", "", "", fmt.Sprintf("%d", len(discussions)) } )
+		fmt.Println(renderTable( tw, r.Theme, text.BgBlue ))
+		return nil
+
+	case "view":
+		detail, err := client.GetDiscussionContext(ctx, r.RepoType, r.RepoID, r.Num)
+		if err != nil {
+			return fmt.Errorf("failed to get discussion #%d: %w", r.Num, err)
+		}
+		if machine {
+			return writeOutput(os.Stdout, r.Output, detail)
+		}
+		displayDiscussion(detail, result.URL, r.Theme)
+		return nil
+
+	case "open":
+		if r.Num > 0 {
+			err = client.ChangeDiscussionStatusContext(ctx, r.RepoType, r.RepoID, r.Num, DiscussionOpen, r.Message)
+			result.Status = DiscussionOpen
+			break
+		}
+		detail, err := client.CreateDiscussionContext(ctx, r.RepoType, r.RepoID, r.Title, r.Message, r.CreatePR)
+		if err != nil {
+			return fmt.Errorf("failed to open discussion: %w", err)
+		}
+		result.Num, result.Status, result.Revision = detail.Num, detail.Status, detail.Revision
+		result.URL = client.DiscussionURL(r.RepoType, r.RepoID, detail.Num)
+	case "comment":
+		err = client.CommentDiscussionContext(ctx, r.RepoType, r.RepoID, r.Num, r.Message)
+	case "close":
+		err = client.ChangeDiscussionStatusContext(ctx, r.RepoType, r.RepoID, r.Num, DiscussionClosed, r.Message)
+		result.Status = DiscussionClosed
+	case "merge":
+		err = client.MergePullRequestContext(ctx, r.RepoType, r.RepoID, r.Num, r.Message)
+		result.Status = DiscussionMerged
+	default:
+		return fmt.Errorf("invalid discussions action: %s", r.Action)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to %s discussion #%d: %w", r.Action, r.Num, err)
+	}
+
+	if machine {
+		return writeOutput(os.Stdout, r.Output, result)
+	}
+	switch {
+	case r.Action == "open" && r.Num == 0 && result.Revision != "":
+		fmt.Printf("🔀 Pull request #%d opened: %s\n", result.Num, result.URL)
+		fmt.Printf("   Upload files to it with -revision %s\n", result.Revision)
+	case r.Action == "open" && r.Num == 0:
+		fmt.Printf("💬 Discussion #%d opened: %s\n", result.Num, result.URL)
+	case r.Action == "open":
+		fmt.Printf("🔓 Discussion #%d reopened\n", r.Num)
+	case r.Action == "comment":
+		fmt.Printf("💬 Comment posted on #%d: %s\n", r.Num, result.URL)
+	case r.Action == "close":
+		fmt.Printf("🔒 Discussion #%d closed\n", r.Num)
+	case r.Action == "merge":
+		fmt.Printf("🎉 Pull request #%d merged\n", r.Num)
+	}
+	return nil
+}
+
+func displayDiscussion(detail *DiscussionDetail, url string, theme string) {
+	kind := "Discussion"
+	if detail.IsPullRequest {
+		kind = "Pull request"
+	}
+
+	tw := table.NewWriter()
+	tw.AppendHeader( table.Row{ fmt.Sprintf( "%s #%d", kind, detail.Num ), detail.Title } )
+	tw.AppendRow( table.Row{ "Status", detail.Status } )
+	tw.AppendRow( table.Row{ "Author", detail.Author.Name } )
+	tw.AppendRow( table.Row{ "Created", detail.CreatedAt.Local().Format( "2006-01-02 15:04" ) } )
+	if detail.Revision != "" {
+		tw.AppendRow( table.Row{ "Revision", paint( theme, 0, 200, 200, detail.Revision ) } )
+	}
+	if detail.Changes != nil {
+		tw.AppendRow( table.Row{ "Target", strings.TrimPrefix( detail.Changes.Base, "refs/heads/" ) } )
+		if detail.Changes.MergeCommitID != "" {
+			tw.AppendRow( table.Row{ "Merge commit", detail.Changes.MergeCommitID } )
+		}
+	}
+	tw.AppendRow( table.Row{ "URL", url } )
+	tw.AppendSeparator()
+	for _, e := range detail.Events {
+		tw.AppendRow( table.Row{ e.Author.Name + "\n" + e.CreatedAt.Local().Format( "2006-01-02 15:04" ), e.Text() } )
+	}
+	tw.AppendFooter( table.Row{ "Events", fmt.Sprintf("%d", len(detail.Events)) } )
+	fmt.Println(renderTable( tw, theme, text.BgBlue ))
+}
+
+// configEntry is a setting of a profile in the output of config list.
+type configEntry struct {
+	Profile string `json:"profile"`
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+}
+
+type configEntries []configEntry
+
+func (entries configEntries) csvHeader() []string {
+	return []string{"profile", "key", "value"}
+}
+
+func (entries configEntries) csvRows() [][]string {
+	rows := make([][]string, len(entries))
+	for i, e := range entries {
+		rows[i] = []string{e.Profile, e.Key, e.Value}
+	}
+	return rows
+}
+
+// manageConfig lists, reads or changes the settings of the config file.
+func manageConfig(r Request) error {
+	path := ConfigPath()
+	config, err := LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	machine, _ := IsMachineOutput(r.Output)
+
+	switch r.Action {
+	case "list":
+		entries := configEntries{}
+		for _, name := range config.ProfileNames() {
+			profile := config.Profiles[name]
+			for _, key := range ProfileKeys {
+				value, _ := profile.Get(key)
+				if key == "token" {
+					value = profile.redactedToken()
+				}
+				if value != "" {
+					entries = append(entries, configEntry{name, key, value})
+				}
+			}
+		}
+		if machine {
+			return writeOutput(os.Stdout, r.Output, entries)
+		}
+
+		tw := table.NewWriter()
+		tw.AppendHeader( table.Row{ "Profile", "Setting", "Value" } )
+		for _, e := range entries {
+			name := e.Profile
+			if name == config.ProfileName("") {
+				name += " (default)"
+			}
+			tw.AppendRow( table.Row{ name, e.Key, e.Value } )
+		}
+		tw.SetCaption( "Profiles of %s", path )
+		tw.SetColumnConfigs( []table.ColumnConfig{ {Number: 1, AutoMerge: true} } )
+		fmt.Println(renderTable( tw, r.Theme, text.BgBlue ))
+
+	case "get":
+		var value string
+		if r.Key == "default-profile" {
+			value = config.ProfileName("")
+		} else {
+			profile, err := config.Profile(r.Profile)
+			if err != nil {
+				return err
+			}
+			if value, err = profile.Get(r.Key); err != nil {
+				return err
+			}
+			if r.Key == "token" {
+				value = profile.redactedToken()
+			}
+		}
+		if machine {
+			return writeOutput(os.Stdout, r.Output, configEntries{{config.ProfileName(r.Profile), r.Key, value}})
+		}
+		fmt.Println(value)
+
+	case "set":
+		if r.Key == "default-profile" {
+			config.DefaultProfile = r.Value
+		} else if err := config.SetProfile(r.Profile).Set(r.Key, r.Value); err != nil {
 			return err
 		}
+		if err := config.Save(path); err != nil {
+			return err
+		}
+		if machine {
+			return nil
+		}
+		if r.Key == "default-profile" {
+			fmt.Printf("⚙️  default profile set to %s in %s\n", r.Value, path)
+		} else {
+			fmt.Printf("⚙️  %s of profile %s set in %s\n", r.Key, config.ProfileName(r.Profile), path)
+		}
 
 	default:
-		return fmt.Errorf("invalid command: %s", reqType)
+		return fmt.Errorf("invalid config action: %s", r.Action)
 	}
 	return nil
 }
 
-func displayMetadata(meta *MetadataResponse) {
+func displayWhoAmI(who *WhoAmIResponse, theme string) {
+	orgs := make([]string, len(who.Orgs))
+	for i, org := range who.Orgs {
+		orgs[i] = org.Name
+	}
+
+	tw := table.NewWriter()
+	tw.AppendHeader( table.Row{ "Account", who.Name } )
+	tw.AppendRow( table.Row{ "Full name", who.FullName } )
+	tw.AppendRow( table.Row{ "Type", who.Type } )
+	if who.Email != "" {
+		tw.AppendRow( table.Row{ "Email", who.Email } )
+	}
+	tw.AppendRow( table.Row{ "Organizations", strings.Join( orgs, ", " ) } )
+	tw.AppendFooter( table.Row{ "Token", who.Auth.AccessToken.DisplayName + " (" + who.Auth.AccessToken.Role + ")" } )
+	fmt.Println(renderTable( tw, theme, text.BgBlue ))
+}
+
+func displayMetadata(meta *MetadataResponse, theme string) {
 
 	description := prepareDescription( meta.Description )
 
 
 	tw := table.NewWriter()
 
-	reset := "\x1b[39m"
+	metaType := strings.TrimPrefix( meta.Type, "sc:" )
 
-	tw.AppendHeader( table.Row{ meta.Type[3:] + " " + meta.Name + " by " + meta.Creator["name"] } )
-	tw.AppendRow( table.Row{ "Name", "\033[38;2;150;200;200;1m" + meta.Name + reset } )
-	tw.AppendRow( table.Row{ "Type", "\033[38;2;100;200;200;1m" + meta.Type[3:] + reset } )
-	tw.AppendRow( table.Row{ "Author","\033[38;2;50;200;200;1m" + meta.Creator["name"] + reset } )
-	tw.AppendRow( table.Row{ "URL", "\033[38;2;0;200;200;1m" + meta.URL + reset} )
-	tw.AppendRow( table.Row{ "License", "\033[38;2;0;150;200;1m" + meta.License + reset } )
+	tw.AppendHeader( table.Row{ metaType + " " + meta.Name + " by " + meta.Creator["name"] } )
+	tw.AppendRow( table.Row{ "Name", paint( theme, 150, 200, 200, meta.Name ) } )
+	tw.AppendRow( table.Row{ "Type", paint( theme, 100, 200, 200, metaType ) } )
+	tw.AppendRow( table.Row{ "Author", paint( theme, 50, 200, 200, meta.Creator["name"] ) } )
+	tw.AppendRow( table.Row{ "URL", paint( theme, 0, 200, 200, meta.URL )} )
+	tw.AppendRow( table.Row{ "License", paint( theme, 0, 150, 200, meta.License ) } )
 	tw.AppendRow( table.Row{ "Description", description } )
 	
 	kw := ""
@@ -118,28 +743,48 @@ func displayMetadata(meta *MetadataResponse) {
 	}
 
 	tw.AppendFooter( table.Row{ "Keywords", kw } )
-	tw.SetStyle( table.StyleColoredDark )
-	tw.Style().Color.Header = text.Colors{ text.BgBlue, text.FgWhite, text.Bold }
-	tw.Style().Color.Footer = text.Colors{ text.BgBlue, text.FgWhite, text.Bold }
 
-	fmt.Println(tw.Render())
+	fmt.Println(renderTable( tw, theme, text.BgBlue ))
 }
 
-func displayStatistics(stat *Statistics, repoName  string) {
+func displayStatistics(stat *Statistics, repoName  string, theme string) {
 
 	tw := table.NewWriter()
 	tw.AppendHeader(table.Row{ fmt.Sprintf("Statistics for dataset %s", repoName) })
-	tw.AppendRow( table.Row{"Partial", fmt.Sprintf("%s", stat.Partial)} )
-	tw.AppendRow( table.Row{"NumExamples", fmt.Sprintf("%s", stat.NumExamples)} )
+	tw.AppendRow( table.Row{"Partial", fmt.Sprintf("%t", stat.Partial)} )
+	tw.AppendRow( table.Row{"NumExamples", fmt.Sprintf("%d", stat.NumExamples)} )
 
 	for k, v := range stat.Statistics {
 		tw.AppendRow( table.Row{ k, fmt.Sprintf("%s", v) } )
 	}
 	
-	tw.SetStyle(table.StyleColoredDark)
-	tw.Style().Color.Header = text.Colors{ text.BgCyan, text.FgWhite, text.Bold }
-	tw.Style().Color.Footer = text.Colors{ text.BgCyan, text.FgWhite, text.Bold }
-	fmt.Println(tw.Render())
+	fmt.Println(renderTable( tw, theme, text.BgCyan ))
+}
+
+// renderTable renders tw in the colours of theme, with the header and the
+// footer on bg.
+func renderTable( tw table.Writer, theme string, bg text.Color ) string {
+	switch theme {
+	case ThemePlain:
+		tw.SetStyle( table.StyleLight )
+		return text.StripEscape( tw.Render() )
+	case ThemeLight:
+		tw.SetStyle( table.StyleColoredBright )
+	default:
+		tw.SetStyle( table.StyleColoredDark )
+	}
+	tw.Style().Color.Header = text.Colors{ bg, text.FgWhite, text.Bold }
+	tw.Style().Color.Footer = text.Colors{ bg, text.FgWhite, text.Bold }
+	return tw.Render()
+}
+
+// paint colours s in the 24-bit colour r, g, b for the tables of theme,
+// and leaves it alone when colours are off with ThemePlain.
+func paint( theme string, r, g, b int, s string ) string {
+	if theme == ThemePlain {
+		return s
+	}
+	return fmt.Sprintf( "\033[38;2;%d;%d;%d;1m%s\x1b[39m", r, g, b, s )
 }
 
 /*
@@ -153,75 +798,501 @@ func getGradientColor( progress float64 ) string {
 }
 
 
-func processFiles(client HuggingFaceClient, files []string, repoType, repoName, action string) error {
+func processFiles(ctx context.Context, client HuggingFaceClient, files []string, repoType, repoName, revision, action string, noCache bool, output string) error {
 
-	bar := progressbar.NewOptions(len(files),
-		progressbar.OptionSetWriter(ansi.NewAnsiStdout()),
-		progressbar.OptionEnableColorCodes(true),
-		progressbar.OptionShowCount(),
-		progressbar.OptionSetWidth(15),
-		progressbar.OptionSetDescription(fmt.Sprintf("[red]Processing %s...[reset]", action)),
-		progressbar.OptionSetTheme(progressbar.Theme{
-			Saucer:        "[blue]-[reset]",
-			SaucerHead:    "[cyan][bold]>[reset]",
-			SaucerPadding: " ",
-			BarStart:      "[",
-			BarEnd:        "]",
-		}))
+	machine, _ := IsMachineOutput(output)
+	report := newTransferReport(machine, action, len(files))
+	client.Progress = report.update
+	_, err := client.downloadFiles(ctx, repoType, repoName, revision, files, ".", noCache, report.finish)
 
-	totalSteps := len(files)
+	return printTransferResults(report.stop(), output, err)
 
-	for i, file := range files {
+}
 
-		color := getGradientColor( float64(i+1) / float64(totalSteps) )
+// printTransferResults writes the results in a machine-readable output, or
+// lists the failures for the table one, and returns err.
+func printTransferResults(results *TransferResults, output string, err error) error {
+	if machine, _ := IsMachineOutput(output); machine {
+		if outErr := writeOutput(os.Stdout, output, results); outErr != nil {
+			return outErr
+		}
+		return err
+	}
+	printTransferFailures(err)
+	return err
+}
 
-		switch action {
-		case "download":
-			content, err := client.DownloadFile(repoType, repoName, file)
-			if err != nil {
-				return fmt.Errorf("failed to download %s: %v", file, err)
-			}
-			if err := ioutil.WriteFile(file, content, 0644); err != nil {
-				return fmt.Errorf("failed to save %s: %v", file, err)
-			}
+// downloadSnapshot downloads the files of the repository matching the patterns of r.
+func downloadSnapshot(ctx context.Context, client HuggingFaceClient, r Request) error {
+	files, err := client.SnapshotFilesContext(ctx, r.RepoType, r.RepoID, r.Revision, r.Include, r.Exclude)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no file of %s matches the given patterns", r.RepoID)
+	}
 
-		case "upload":
-			content, err := ioutil.ReadFile(file)
-			if err != nil {
-				return fmt.Errorf("failed to read %s: %v", file, err)
+	machine, _ := IsMachineOutput(r.Output)
+	report := newTransferReport(machine, "snapshot", len(files))
+	client.Progress = report.update
+	dir, err := client.downloadFiles(ctx, r.RepoType, r.RepoID, r.Revision, files, r.LocalDir, false, report.finish)
+	results := report.stop()
+	if machine || err != nil {
+		return printTransferResults(results, r.Output, err)
+	}
+
+	if r.LocalDir != "" {
+		dir = r.LocalDir
+	}
+	fmt.Printf("📦 %d files of %s downloaded to %s\n", len(files), r.RepoID, dir)
+	return nil
+}
+
+// verifyLocalDir checks r.LocalDir against the repository and lists the differences.
+func verifyLocalDir(ctx context.Context, client HuggingFaceClient, r Request) error {
+	dir := r.LocalDir
+	if dir == "" {
+		dir = "."
+	}
+	report, err := client.VerifyLocalDirContext(ctx, r.RepoType, r.RepoID, r.Revision, dir)
+	if err != nil {
+		return err
+	}
+	machine, _ := IsMachineOutput(r.Output)
+	if machine {
+		if err := writeOutput(os.Stdout, r.Output, report); err != nil {
+			return err
+		}
+	} else if report.OK() {
+		fmt.Printf("✅ %d files of %s verified in %s\n", len(report.Verified), r.RepoID, dir)
+	}
+	if report.OK() {
+		return nil
+	}
+	if machine {
+		return verifyError(dir, r.RepoID, report)
+	}
+
+	tw := table.NewWriter()
+	tw.AppendHeader( table.Row{ "File", "Status" } )
+	for _, file := range report.Missing {
+		tw.AppendRow( table.Row{ file, paint( r.Theme, 200, 200, 0, "missing" ) } )
+	}
+	for _, file := range report.Extra {
+		tw.AppendRow( table.Row{ file, paint( r.Theme, 0, 200, 200, "extra" ) } )
+	}
+	for _, file := range report.Corrupted {
+		tw.AppendRow( table.Row{ file, paint( r.Theme, 200, 0, 0, "corrupted" ) } )
+	}
+	tw.AppendFooter( table.Row{ "Verified", fmt.Sprintf("%d", len(report.Verified)) } )
+	fmt.Println(renderTable( tw, r.Theme, text.BgBlue ))
+
+	return verifyError(dir, r.RepoID, report)
+}
+
+func verifyError(dir, repoID string, report *VerifyReport) error {
+	err := fmt.Errorf("%s does not match %s: %d missing, %d extra, %d corrupted files",
+		dir, repoID, len(report.Missing), len(report.Extra), len(report.Corrupted))
+	if len(report.Corrupted) > 0 {
+		err = fmt.Errorf("%w: %w", ErrChecksumMismatch, err)
+	}
+	return err
+}
+
+// uploadFiles pushes all files of r in a single commit, so that a failure
+// halfway leaves the repository untouched.
+func uploadFiles(ctx context.Context, client HuggingFaceClient, r Request) error {
+	entries, err := uploadList(r)
+	if err != nil {
+		return err
+	}
+	machine, _ := IsMachineOutput(r.Output)
+	if r.DryRun {
+		return printUploadList(entries, r)
+	}
+
+	summary := r.CommitMessage
+	if summary == "" {
+		if len(entries) == 1 {
+			summary = "Upload " + entries[0].Path
+		} else {
+			summary = fmt.Sprintf("Upload %d files with hugger", len(entries))
+		}
+	}
+
+	report := newTransferReport(machine, "upload", len(entries))
+	client.Progress = report.update
+	commit := client.NewCommit(r.RepoType, r.RepoID, r.Revision, summary)
+	commit.Description = r.CommitDescription
+	commit.CreatePR = r.CreatePR
+	localPaths := make(map[string]string, len(entries))
+	for _, e := range entries {
+		commit.AddLocalFile(e.Path, e.LocalPath)
+		localPaths[e.Path] = e.LocalPath
+	}
+	commit.Progress = func(file string) {
+		report.finish(file, localPaths[file], nil)
+	}
+
+	info, err := pushCommit(ctx, commit, report)
+	results := report.stop()
+	if machine || err != nil {
+		return printTransferResults(results, r.Output, err)
+	}
+
+	fmt.Println()
+	for _, file := range info.Ignored {
+		fmt.Printf("🙈 %s is ignored by the repository's .gitignore, skipped\n", file)
+	}
+	if info.CommitURL != "" {
+		fmt.Printf("🚀 Uploaded %d files in one commit: %s\n", len(entries)-len(info.Ignored), info.CommitURL)
+	}
+	printPullRequest(info)
+	return nil
+}
+
+// uploadEntry is a local file that upload sends, and its path in the repository.
+type uploadEntry struct {
+	Path      string `json:"path"`
+	LocalPath string `json:"localPath"`
+	Size      int64  `json:"size"`
+}
+
+type uploadListing []uploadEntry
+
+func (entries uploadListing) csvHeader() []string {
+	return []string{"path", "localPath", "size"}
+}
+
+func (entries uploadListing) csvRows() [][]string {
+	rows := make([][]string, len(entries))
+	for i, e := range entries {
+		rows[i] = []string{e.Path, e.LocalPath, fmt.Sprintf("%d", e.Size)}
+	}
+	return rows
+}
+
+// uploadList maps the files of r to their paths in the repository, relative
+// to r.LocalRoot and under r.PathInRepo, and keeps those that pass the
+// include and exclude patterns.
+func uploadList(r Request) (uploadListing, error) {
+	root := r.LocalRoot
+	if root == "" {
+		root = "."
+	}
+	var entries uploadListing
+	for _, file := range r.Files {
+		pathInRepo, err := RepoPath(root, file, r.PathInRepo)
+		if err != nil {
+			if r.LocalRoot == "" {
+				err = fmt.Errorf("%w, choose another one with -local-root", err)
 			}
-			if err := client.UploadFile(repoType, repoName, file, content); err != nil {
-				return fmt.Errorf("failed to upload %s: %v", file, err)
+			return nil, err
+		}
+		if len(FilterPaths([]string{pathInRepo}, r.Include, r.Exclude)) > 0 {
+			entries = append(entries, uploadEntry{Path: pathInRepo, LocalPath: file})
+		}
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no file to upload matches the given patterns")
+	}
+	return entries, nil
+}
+
+// printUploadList shows the files that upload would send, without sending them.
+func printUploadList(entries uploadListing, r Request) error {
+	var totalSize int64
+	for i, e := range entries {
+		info, err := os.Stat(e.LocalPath)
+		if err != nil {
+			return err
+		}
+		entries[i].Size = info.Size()
+		totalSize += info.Size()
+	}
+	if machine, _ := IsMachineOutput(r.Output); machine {
+		return writeOutput(os.Stdout, r.Output, entries)
+	}
+
+	tw := table.NewWriter()
+	tw.AppendHeader( table.Row{ "Dry run: upload to " + r.RepoID, "Local file", "Size" } )
+	for _, e := range entries {
+		tw.AppendRow( table.Row{ e.Path, e.LocalPath, progress.FormatBytes( e.Size ) } )
+	}
+	tw.AppendFooter( table.Row{ fmt.Sprintf("%d files", len(entries)), "", progress.FormatBytes( totalSize ) } )
+	tw.SetColumnConfigs( []table.ColumnConfig{ {Number: 3, Align: text.AlignRight} } )
+	fmt.Println(renderTable( tw, r.Theme, text.BgBlue ))
+	return nil
+}
+
+// pushCommit pushes commit and records the failed and the ignored files, and
+// the commit itself, in report.
+func pushCommit(ctx context.Context, commit *CommitBuilder, report *transferReport) (*CommitInfo, error) {
+	info, err := commit.Push(ctx)
+	var transferErr *TransferError
+	if errors.As(err, &transferErr) {
+		for _, f := range transferErr.Failures {
+			report.finish(f.Path, "", f.Err)
+		}
+	}
+	if info != nil {
+		for _, file := range info.Ignored {
+			report.ignore(file)
+		}
+		if info.CommitURL != "" {
+			report.Commit = info
+		}
+	}
+	return info, err
+}
+
+func printPullRequest(info *CommitInfo) {
+	if info.PullRequestURL != "" {
+		fmt.Printf("🔀 Pull request opened: %s\n", info.PullRequestURL)
+		fmt.Printf("   Upload more files to it with -revision %s\n", info.PullRequestRevision)
+	}
+}
+
+// syncFolder makes the repository match r.LocalDir with r.Action SyncPush,
+// or the other way round with SyncPull, transferring only what differs.
+func syncFolder(ctx context.Context, client HuggingFaceClient, r Request) error {
+	dir := r.LocalDir
+	if dir == "" {
+		dir = "."
+	}
+	plan, err := client.PlanSyncContext(ctx, r.Action, r.RepoType, r.RepoID, r.Revision, dir, r.Delete)
+	if err != nil {
+		return err
+	}
+
+	machine, _ := IsMachineOutput(r.Output)
+	if plan.Empty() && !machine {
+		fmt.Printf("✅ %s and %s are already in sync, %d files\n", dir, r.RepoID, plan.Unchanged)
+		return nil
+	}
+	if r.DryRun || plan.Empty() {
+		if machine {
+			return writeOutput(os.Stdout, r.Output, plan)
+		}
+		displaySyncPlan(plan, dir, r.RepoID, r.Theme)
+		return nil
+	}
+
+	if len(plan.Deleted) > 0 && !r.Yes {
+		fmt.Fprintf(os.Stderr, "%s %s deletes:\n", r.Action, dir)
+		for _, file := range plan.Deleted {
+			fmt.Fprintf(os.Stderr, "\t%s\n", file)
+		}
+		if r.Confirm == nil || !r.Confirm(fmt.Sprintf("Delete %d files?", len(plan.Deleted))) {
+			return fmt.Errorf("sync cancelled, pass -yes to delete without confirmation")
+		}
+	}
+
+	files := plan.Transfers()
+	if r.Action == SyncPull {
+		return pullFolder(ctx, client, r, plan, dir)
+	}
+
+	summary := r.CommitMessage
+	if summary == "" {
+		summary = fmt.Sprintf("Sync %d files with hugger", len(files)+len(plan.Deleted))
+	}
+	report := newTransferReport(machine, "upload", len(files))
+	client.Progress = report.update
+	commit := client.NewCommit(r.RepoType, r.RepoID, r.Revision, summary)
+	commit.Description = r.CommitDescription
+	commit.CreatePR = r.CreatePR
+	for _, file := range files {
+		commit.AddLocalFile(file, filepath.Join(dir, filepath.FromSlash(file)))
+	}
+	for _, file := range plan.Deleted {
+		commit.DeleteFile(file)
+	}
+	commit.Progress = func(file string) {
+		report.finish(file, filepath.Join(dir, filepath.FromSlash(file)), nil)
+	}
+
+	info, err := pushCommit(ctx, commit, report)
+	if err == nil {
+		for _, file := range plan.Deleted {
+			report.set(file, "deleted", "", nil)
+		}
+	}
+	results := report.stop()
+	if machine || err != nil {
+		return printTransferResults(results, r.Output, err)
+	}
+
+	fmt.Println()
+	for _, file := range info.Ignored {
+		fmt.Printf("🙈 %s is ignored by the repository's .gitignore, skipped\n", file)
+	}
+	if info.CommitURL != "" {
+		fmt.Printf("🚀 %s pushed to %s in one commit, %d files uploaded and %d deleted: %s\n",
+			dir, r.RepoID, len(files)-len(info.Ignored), len(plan.Deleted), info.CommitURL)
+	}
+	printPullRequest(info)
+	return nil
+}
+
+// pullFolder downloads the new and changed files of plan to dir, then
+// removes the deleted ones.
+func pullFolder(ctx context.Context, client HuggingFaceClient, r Request, plan *SyncPlan, dir string) error {
+	machine, _ := IsMachineOutput(r.Output)
+	files := plan.Transfers()
+	report := newTransferReport(machine, "download", len(files))
+	client.Progress = report.update
+	_, err := client.downloadFiles(ctx, r.RepoType, r.RepoID, r.Revision, files, dir, false, report.finish)
+	if err == nil {
+		for _, file := range plan.Deleted {
+			path := filepath.Join(dir, filepath.FromSlash(file))
+			if err = os.Remove(path); err != nil {
+				break
 			}
+			report.set(file, "deleted", path, nil)
 		}
-		bar.Describe( fmt.Sprintf("%s Processing %s...[reset]", color, action) )
-		bar.Add(1)
 	}
+	results := report.stop()
+	if machine || err != nil {
+		return printTransferResults(results, r.Output, err)
+	}
+
+	fmt.Printf("📥 %s pulled to %s, %d files downloaded and %d deleted\n", r.RepoID, dir, len(files), len(plan.Deleted))
 	return nil
+}
+
+func displaySyncPlan(plan *SyncPlan, dir, repoID string, theme string) {
+	from, to := dir, repoID
+	if plan.Direction == SyncPull {
+		from, to = repoID, dir
+	}
 
+	tw := table.NewWriter()
+	tw.AppendHeader( table.Row{ fmt.Sprintf( "Dry run: %s %s to %s", plan.Direction, from, to ), "Change" } )
+	for _, row := range plan.csvRows() {
+		change := row[1]
+		switch change {
+		case "new":
+			change = paint( theme, 0, 200, 0, change )
+		case "changed":
+			change = paint( theme, 200, 200, 0, change )
+		case "deleted":
+			change = paint( theme, 200, 0, 0, change )
+		}
+		tw.AppendRow( table.Row{ row[0], change } )
+	}
+	tw.AppendFooter( table.Row{ "Unchanged", fmt.Sprintf("%d", plan.Unchanged) } )
+	fmt.Println(renderTable( tw, theme, text.BgBlue ))
 }
 
-func manageRepo(client HuggingFaceClient, repoType, repoName, action string, private bool) error {
+// repoResult is the machine-readable output of the repo subcommand.
+type repoResult struct {
+	Action   string `json:"action"`
+	RepoID   string `json:"repoId"`
+	RepoType string `json:"repoType"`
+	// Private is only set by the actions that decide it, create and
+	// settings with a new visibility.
+	Private  *bool  `json:"private,omitempty"`
+	Gated    string `json:"gated,omitempty"`
+	ToRepoID string `json:"toRepoId,omitempty"`
+	DryRun   bool   `json:"dryRun,omitempty"`
+}
+
+func (r *repoResult) csvHeader() []string {
+	return []string{"action", "repoId", "repoType", "private", "gated", "toRepoId", "dryRun"}
+}
+
+func (r *repoResult) csvRows() [][]string {
+	private := ""
+	if r.Private != nil {
+		private = fmt.Sprintf("%t", *r.Private)
+	}
+	return [][]string{{r.Action, r.RepoID, r.RepoType, private, r.Gated, r.ToRepoID, fmt.Sprintf("%t", r.DryRun)}}
+}
+
+func manageRepo(ctx context.Context, client HuggingFaceClient, r Request) error {
+	repoType, repoName, action, private, output := r.RepoType, r.RepoID, r.Action, r.Private, r.Output
+	machine, _ := IsMachineOutput(output)
 	switch action {
 	case "create":
-		if err := client.CreateRepo(repoType, repoName, private); err != nil {
-			return fmt.Errorf("failed to create repository: %v", err)
+		if err := client.CreateRepoContext(ctx, repoType, repoName, private); err != nil {
+			return fmt.Errorf("failed to create repository: %w", err)
+		}
+		if machine {
+			return writeOutput(os.Stdout, output, &repoResult{Action: "create", RepoID: repoName, RepoType: repoType, Private: &private})
 		}
 		fmt.Printf("✨ Repository %s/%s created successfully!\n", repoType, repoName)
 
 	case "delete":
-		if err := client.DeleteRepo(repoName); err != nil {
-			return fmt.Errorf("failed to delete repository: %v", err)
+		if err := client.DeleteRepoContext(ctx, repoName); err != nil {
+			return fmt.Errorf("failed to delete repository: %w", err)
+		}
+		if machine {
+			return writeOutput(os.Stdout, output, &repoResult{Action: "delete", RepoID: repoName, RepoType: repoType})
 		}
 		fmt.Printf("🗑️  Repository %s/%s deleted successfully!\n", repoType, repoName)
 
+	case "settings":
+		var settings RepoSettings
+		switch r.Visibility {
+		case "":
+		case "public", "private":
+			private = r.Visibility == "private"
+			settings.Private = &private
+		default:
+			return fmt.Errorf("invalid visibility %q, expected public or private", r.Visibility)
+		}
+		switch r.Gated {
+		case "", GatedAuto, GatedManual, GatedOff:
+		default:
+			return fmt.Errorf("invalid gated mode %q, expected auto, manual or false", r.Gated)
+		}
+		settings.Gated = r.Gated
+		if err := client.UpdateRepoSettingsContext(ctx, repoType, repoName, settings); err != nil {
+			return fmt.Errorf("failed to update repository settings: %w", err)
+		}
+		if machine {
+			return writeOutput(os.Stdout, output, &repoResult{Action: "settings", RepoID: repoName, RepoType: repoType, Private: settings.Private, Gated: r.Gated})
+		}
+		if settings.Private != nil {
+			fmt.Printf("🔒 Repository %s/%s is now %s\n", repoType, repoName, r.Visibility)
+		}
+		switch r.Gated {
+		case GatedOff:
+			fmt.Printf("🔓 Gated access of %s/%s turned off\n", repoType, repoName)
+		case GatedAuto, GatedManual:
+			fmt.Printf("🚧 Gated access of %s/%s turned on, with %s approval\n", repoType, repoName, r.Gated)
+		}
+
+	case "move":
+		if r.ToRepoID == "" {
+			return fmt.Errorf("repo -action move requires the new repo id")
+		}
+		result := &repoResult{Action: "move", RepoID: repoName, RepoType: repoType, ToRepoID: r.ToRepoID, DryRun: r.DryRun}
+		if r.DryRun {
+			if machine {
+				return writeOutput(os.Stdout, output, result)
+			}
+			fmt.Printf("🔍 Dry run: %s/%s would be moved to %s/%s\n", repoType, repoName, repoType, r.ToRepoID)
+			return nil
+		}
+		if err := client.MoveRepoContext(ctx, repoType, repoName, r.ToRepoID); err != nil {
+			return fmt.Errorf("failed to move repository: %w", err)
+		}
+		if machine {
+			return writeOutput(os.Stdout, output, result)
+		}
+		fmt.Printf("🚚 Repository %s/%s moved to %s/%s\n", repoType, repoName, repoType, r.ToRepoID)
+
 	default:
 		return fmt.Errorf("invalid repo action: %s", action)
 	}
 	return nil
 }
 
-func manageRepoFiles(client HuggingFaceClient, repoType, repoName string, files []string, action string) error {
+func manageRepoFiles(ctx context.Context, client HuggingFaceClient, r Request) error {
+	repoType, repoName, revision, files, action, output := r.RepoType, r.RepoID, r.Revision, r.Files, r.Action, r.Output
+	machine, _ := IsMachineOutput(output)
 	switch action {
 	case "list":
 		filepath := "/"
@@ -231,44 +1302,84 @@ func manageRepoFiles(client HuggingFaceClient, repoType, repoName string, files
 			}
 		}
 
-		repoFiles, err := client.ListFilesInRepo(repoType, repoName, filepath, false)
+		repoFiles, err := client.ListFilesInRepoContext(ctx, repoType, repoName, revision, filepath, false)
 		if err != nil {
-			return fmt.Errorf("failed to list files: %v", err)
+			return fmt.Errorf("failed to list files: %w", err)
+		}
+		if machine {
+			if repoFiles == nil {
+				repoFiles = []HFFile{}
+			}
+			return writeOutput(os.Stdout, output, fileListing(repoFiles))
 		}
 
 		tw := table.NewWriter()
-		tw.AppendHeader( table.Row{"Directory listing of " + filepath} )
+		tw.AppendHeader( table.Row{"Directory listing of " + filepath, "Size", "Last commit", ""} )
 
 		directoriesCount := 0
 		filesCount := 0
+		var totalSize int64
 
 		for _, file := range repoFiles {
-			if file[len(file)-1] == '/' { // directory
-				file = "\033[38;2;0;200;200;1m" + file 
+			name, size := file.Path, ""
+			if file.Type == "directory" {
+				name = paint( r.Theme, 0, 200, 200, name + "/" )
 				directoriesCount++
 			} else {
+				size = progress.FormatBytes( int64(file.Size) )
+				totalSize += int64(file.Size)
 				filesCount++
 			}
-			tw.AppendRow( table.Row{ file } )
+
+			date, title := "", ""
+			if file.LastCommit != nil {
+				date = file.LastCommit.Date.Local().Format("2006-01-02 15:04")
+				title = file.LastCommit.Title
+			}
+			tw.AppendRow( table.Row{ name, size, date, title } )
 		}
 
-		//tw.AppendFooter( table.Row{"Folders:", fmt.Sprintf("%d", directoriesCount) } )
-		//tw.AppendFooter( table.Row{"Files:", fmt.Sprintf("%d", filesCount) } )
-		tw.AppendFooter( table.Row{"Total", fmt.Sprintf("%d", len(repoFiles))} )
+		tw.AppendFooter( table.Row{ "Total", progress.FormatBytes(totalSize),
+			fmt.Sprintf("%d files, %d folders", filesCount, directoriesCount), "" } )
 
-		tw.SetStyle( table.StyleColoredDark )
-		tw.Style().Color.Header = text.Colors{ text.BgBlue, text.FgWhite, text.Bold }
-		tw.Style().Color.Footer = text.Colors{ text.BgBlue, text.FgWhite, text.Bold }
+		tw.SetColumnConfigs( []table.ColumnConfig{ {Number: 2, Align: text.AlignRight} } )
 
-		fmt.Println(tw.Render())
+		fmt.Println(renderTable( tw, r.Theme, text.BgBlue ))
 
 	case "delete":
+		if len(files) == 0 {
+			return fmt.Errorf("no file to delete")
+		}
+		summary := r.CommitMessage
+		if summary == "" {
+			if len(files) == 1 {
+				summary = "Delete " + files[0]
+			} else {
+				summary = fmt.Sprintf("Delete %d files with hugger", len(files))
+			}
+		}
+		// Every file goes in one commit, so that either all are deleted or none
+		commit := client.NewCommit(repoType, repoName, revision, summary)
+		commit.Description = r.CommitDescription
 		for _, file := range files {
-			if err := client.DeleteFile(repoType, repoName, file); err != nil {
-				return fmt.Errorf("failed to delete %s: %v", file, err)
+			commit.DeleteFile(file)
+		}
+		info, err := commit.Push(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to delete files: %w", err)
+		}
+
+		results := &TransferResults{Commit: info, Files: []*TransferResult{}}
+		for _, file := range files {
+			if machine {
+				results.Files = append(results.Files, &TransferResult{Path: file, Status: "deleted"})
+				continue
 			}
 			fmt.Printf("🗑️  Deleted %s\n", file)
 		}
+		if machine {
+			return writeOutput(os.Stdout, output, results)
+		}
 
 	default:
 		return fmt.Errorf("invalid file action: %s", action)
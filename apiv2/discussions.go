@@ -0,0 +1,346 @@
+package apiv2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Statuses of a Discussion.
+const (
+	DiscussionOpen   = "open"
+	DiscussionClosed = "closed"
+	DiscussionMerged = "merged"
+	DiscussionDraft  = "draft"
+)
+
+// Discussion is a discussion or a pull request of a repository.
+type Discussion struct {
+	Num           int              `json:"num"`
+	Title         string           `json:"title"`
+	Status        string           `json:"status"`
+	Author        DiscussionAuthor `json:"author"`
+	IsPullRequest bool             `json:"isPullRequest"`
+	CreatedAt     time.Time        `json:"createdAt"`
+	NumComments   int              `json:"numComments,omitempty"`
+	// Revision is refs/pr/N for a pull request, the revision that uploads
+	// target to add commits to it.
+	Revision string `json:"revision,omitempty"`
+}
+
+// DiscussionAuthor is the Hub user who opened a discussion or wrote an event.
+type DiscussionAuthor struct {
+	Name string `json:"name"`
+}
+
+// DiscussionDetail is a discussion with its events, as GetDiscussion returns it.
+type DiscussionDetail struct {
+	Discussion
+	Events []DiscussionEvent `json:"events"`
+	// Changes are the branch a pull request is merged into, and the merge
+	// commit once it is merged.
+	Changes *DiscussionChanges `json:"changes,omitempty"`
+}
+
+// DiscussionChanges are the target of a pull request.
+type DiscussionChanges struct {
+	Base          string `json:"base"`
+	MergeCommitID string `json:"mergeCommitId,omitempty"`
+}
+
+// DiscussionEvent is a comment, a status change, a commit or a title change
+// of a discussion, as told by Type.
+type DiscussionEvent struct {
+	ID        string              `json:"id"`
+	Type      string              `json:"type"`
+	CreatedAt time.Time           `json:"createdAt"`
+	Author    DiscussionAuthor    `json:"author"`
+	Data      DiscussionEventData `json:"data"`
+}
+
+// DiscussionEventData holds the fields of the event type: Latest and Hidden
+// for a comment, Status for a status change, Oid and Subject for a commit,
+// From and To for a title change.
+type DiscussionEventData struct {
+	Latest  *DiscussionComment `json:"latest,omitempty"`
+	Hidden  bool               `json:"hidden,omitempty"`
+	Status  string             `json:"status,omitempty"`
+	Oid     string             `json:"oid,omitempty"`
+	Subject string             `json:"subject,omitempty"`
+	From    string             `json:"from,omitempty"`
+	To      string             `json:"to,omitempty"`
+}
+
+// DiscussionComment is the latest edit of a comment.
+type DiscussionComment struct {
+	Raw string `json:"raw"`
+}
+
+// Text describes the event in one line, or the whole text of a comment.
+func (e *DiscussionEvent) Text() string {
+	switch e.Type {
+	case "comment":
+		if e.Data.Hidden {
+			return "(hidden comment)"
+		}
+		if e.Data.Latest != nil {
+			return e.Data.Latest.Raw
+		}
+		return ""
+	case "status-change":
+		return "changed the status to " + e.Data.Status
+	case "commit":
+		return fmt.Sprintf("pushed %s: %s", shortCommit(e.Data.Oid), e.Data.Subject)
+	case "title-change":
+		return fmt.Sprintf("renamed from %q to %q", e.Data.From, e.Data.To)
+	}
+	return e.Type
+}
+
+// discussionListing is the output of discussions list.
+type discussionListing []Discussion
+
+func (discussions discussionListing) csvHeader() []string {
+	return []string{"num", "pullRequest", "status", "title", "author", "createdAt", "revision"}
+}
+
+func (discussions discussionListing) csvRows() [][]string {
+	rows := make([][]string, len(discussions))
+	for i, d := range discussions {
+		rows[i] = []string{strconv.Itoa(d.Num), strconv.FormatBool(d.IsPullRequest), d.Status, d.Title, d.Author.Name, d.CreatedAt.Format(time.RFC3339), d.Revision}
+	}
+	return rows
+}
+
+func (detail *DiscussionDetail) csvHeader() []string {
+	return []string{"num", "eventId", "type", "createdAt", "author", "text"}
+}
+
+func (detail *DiscussionDetail) csvRows() [][]string {
+	rows := make([][]string, len(detail.Events))
+	for i, e := range detail.Events {
+		rows[i] = []string{strconv.Itoa(detail.Num), e.ID, e.Type, e.CreatedAt.Format(time.RFC3339), e.Author.Name, e.Text()}
+	}
+	return rows
+}
+
+// DiscussionListOptions narrow down ListDiscussions. Empty fields do not filter.
+type DiscussionListOptions struct {
+	// Status is "open", "closed" or "all".
+	Status string
+	// Type is "discussion", "pull_request" or "all".
+	Type   string
+	Author string
+}
+
+func (client *HuggingFaceClient) ListDiscussions(repoType, repoID string, opts DiscussionListOptions) ([]Discussion, error) {
+	return client.ListDiscussionsContext(context.Background(), repoType, repoID, opts)
+}
+
+// ListDiscussionsContext returns the discussions and pull requests of a
+// repository, newest first, going through every page.
+func (client *HuggingFaceClient) ListDiscussionsContext(ctx context.Context, repoType, repoID string, opts DiscussionListOptions) ([]Discussion, error) {
+	query := url.Values{}
+	if opts.Status != "" {
+		query.Set("status", opts.Status)
+	}
+	if opts.Type != "" {
+		query.Set("type", opts.Type)
+	}
+	if opts.Author != "" {
+		query.Set("author", opts.Author)
+	}
+
+	discussions := []Discussion{}
+	for page := 0; ; page++ {
+		query.Set("p", strconv.Itoa(page))
+		endpoint := fmt.Sprintf("%s/api/%s/%s/discussions?%s", client.endpoint(), repoType+"s", repoID, query.Encode())
+		resp, err := client.sendDiscussionRequest(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var res struct {
+			Discussions []Discussion `json:"discussions"`
+			Count       int          `json:"count"`
+			Start       int          `json:"start"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&res)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode discussions: %w", err)
+		}
+		for _, d := range res.Discussions {
+			discussions = append(discussions, d.withRevision())
+		}
+		if len(res.Discussions) == 0 || res.Start+len(res.Discussions) >= res.Count {
+			return discussions, nil
+		}
+	}
+}
+
+func (client *HuggingFaceClient) GetDiscussion(repoType, repoID string, num int) (*DiscussionDetail, error) {
+	return client.GetDiscussionContext(context.Background(), repoType, repoID, num)
+}
+
+// GetDiscussionContext returns discussion num of a repository with all its events.
+func (client *HuggingFaceClient) GetDiscussionContext(ctx context.Context, repoType, repoID string, num int) (*DiscussionDetail, error) {
+	resp, err := client.sendDiscussionRequest(ctx, "GET", client.discussionEndpoint(repoType, repoID, num, ""), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	detail := &DiscussionDetail{}
+	if err := json.NewDecoder(resp.Body).Decode(detail); err != nil {
+		return nil, fmt.Errorf("failed to decode discussion: %w", err)
+	}
+	detail.Discussion = detail.Discussion.withRevision()
+	if detail.Events == nil {
+		detail.Events = []DiscussionEvent{}
+	}
+	return detail, nil
+}
+
+func (client *HuggingFaceClient) CreateDiscussion(repoType, repoID, title, description string, pullRequest bool) (*DiscussionDetail, error) {
+	return client.CreateDiscussionContext(context.Background(), repoType, repoID, title, description, pullRequest)
+}
+
+// CreateDiscussionContext opens a discussion, or a draft pull request whose
+// revision uploads can then push commits to.
+func (client *HuggingFaceClient) CreateDiscussionContext(ctx context.Context, repoType, repoID, title, description string, pullRequest bool) (*DiscussionDetail, error) {
+	payload := map[string]any{"title": title, "description": description, "pullRequest": pullRequest}
+	endpoint := fmt.Sprintf("%s/api/%s/%s/discussions", client.endpoint(), repoType+"s", repoID)
+	resp, err := client.sendDiscussionRequest(ctx, "POST", endpoint, payload)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var created struct {
+		Num int `json:"num"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return nil, fmt.Errorf("failed to decode discussion: %w", err)
+	}
+	return client.GetDiscussionContext(ctx, repoType, repoID, created.Num)
+}
+
+func (client *HuggingFaceClient) CommentDiscussion(repoType, repoID string, num int, comment string) error {
+	return client.CommentDiscussionContext(context.Background(), repoType, repoID, num, comment)
+}
+
+func (client *HuggingFaceClient) CommentDiscussionContext(ctx context.Context, repoType, repoID string, num int, comment string) error {
+	if strings.TrimSpace(comment) == "" {
+		return fmt.Errorf("empty comment")
+	}
+	payload := map[string]any{"comment": comment}
+	resp, err := client.sendDiscussionRequest(ctx, "POST", client.discussionEndpoint(repoType, repoID, num, "/comment"), payload)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (client *HuggingFaceClient) ChangeDiscussionStatus(repoType, repoID string, num int, status, comment string) error {
+	return client.ChangeDiscussionStatusContext(context.Background(), repoType, repoID, num, status, comment)
+}
+
+// ChangeDiscussionStatusContext closes or reopens a discussion, with an
+// optional comment. status is DiscussionOpen or DiscussionClosed.
+func (client *HuggingFaceClient) ChangeDiscussionStatusContext(ctx context.Context, repoType, repoID string, num int, status, comment string) error {
+	if status != DiscussionOpen && status != DiscussionClosed {
+		return fmt.Errorf("invalid discussion status %q, expected open or closed", status)
+	}
+	payload := map[string]any{"status": status}
+	if comment != "" {
+		payload["comment"] = comment
+	}
+	resp, err := client.sendDiscussionRequest(ctx, "PATCH", client.discussionEndpoint(repoType, repoID, num, "/status"), payload)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (client *HuggingFaceClient) MergePullRequest(repoType, repoID string, num int, comment string) error {
+	return client.MergePullRequestContext(context.Background(), repoType, repoID, num, comment)
+}
+
+// MergePullRequestContext merges pull request num into its target branch,
+// with an optional comment.
+func (client *HuggingFaceClient) MergePullRequestContext(ctx context.Context, repoType, repoID string, num int, comment string) error {
+	payload := map[string]any{}
+	if comment != "" {
+		payload["comment"] = comment
+	}
+	resp, err := client.sendDiscussionRequest(ctx, "POST", client.discussionEndpoint(repoType, repoID, num, "/merge"), payload)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// DiscussionURL returns the page of discussion num on the Hub.
+func (client *HuggingFaceClient) DiscussionURL(repoType, repoID string, num int) string {
+	return fmt.Sprintf("%s/%s%s/discussions/%d", client.endpoint(), lfsRepoPrefix(repoType), repoID, num)
+}
+
+func (client *HuggingFaceClient) discussionEndpoint(repoType, repoID string, num int, action string) string {
+	return fmt.Sprintf("%s/api/%s/%s/discussions/%d%s", client.endpoint(), repoType+"s", repoID, num, action)
+}
+
+// sendDiscussionRequest sends payload, if not nil, as JSON. Changes are only
+// retried when the Hub rejected them, so that a comment is never posted twice.
+func (client *HuggingFaceClient) sendDiscussionRequest(ctx context.Context, method, endpoint string, payload map[string]any) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		data, _ := json.Marshal(payload)
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discussion request: %w", err)
+	}
+	if err := client.authorize(req); err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if method == "GET" {
+		return client.doRequest(req)
+	}
+	return client.doRequestRetry(req, retryRejected)
+}
+
+func (d Discussion) withRevision() Discussion {
+	if d.IsPullRequest {
+		d.Revision = pullRequestRevision(d.Num)
+	}
+	return d
+}
+
+// pullRequestRevision returns the revision that holds the commits of pull request num.
+func pullRequestRevision(num int) string {
+	return fmt.Sprintf("refs/pr/%d", num)
+}
+
+// pullRequestNum reads the number of a pull request from its URL, which
+// ends with /discussions/N.
+func pullRequestNum(pullRequestURL string) (int, bool) {
+	i := strings.LastIndex(pullRequestURL, "/discussions/")
+	if i < 0 {
+		return 0, false
+	}
+	num, err := strconv.Atoi(strings.TrimRight(pullRequestURL[i+len("/discussions/"):], "/"))
+	return num, err == nil
+}
@@ -0,0 +1,26 @@
+package apiv2
+
+import "testing"
+
+func TestPullRequestNum(t *testing.T) {
+	tests := []struct {
+		url    string
+		want   int
+		wantOK bool
+	}{
+		{"https://huggingface.co/user/repo/discussions/12", 12, true},
+		{"https://huggingface.co/datasets/user/repo/discussions/3/", 3, true},
+		{"https://huggingface.co/user/repo/discussions/abc", 0, false},
+		{"https://huggingface.co/user/repo/commit/1234", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := pullRequestNum(tt.url)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("pullRequestNum(%q) = %d, %v, want %d, %v", tt.url, got, ok, tt.want, tt.wantOK)
+		}
+	}
+	if got := pullRequestRevision(12); got != "refs/pr/12" {
+		t.Errorf("pullRequestRevision(12) = %q, want refs/pr/12", got)
+	}
+}
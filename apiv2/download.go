@@ -0,0 +1,298 @@
+package apiv2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// IncompleteSuffix is appended to a file while it is being downloaded.
+// An interrupted download leaves it behind and the next one resumes from it.
+const IncompleteSuffix = ".incomplete"
+
+// ETagSuffix is appended to the .incomplete file to name the file that holds
+// the ETag of the download, so that a resume never appends a newer version
+// of the file to an older one.
+const ETagSuffix = ".etag"
+
+func (client *HuggingFaceClient) resolveURL(repoType, repoName, revision, filePath string) string {
+	return fmt.Sprintf("%s/%s/%s/resolve/%s/%s", client.endpoint(), repoType+"s", repoName,
+		escapeRevision(revision), escapeRepoPath(filePath))
+}
+
+// openDownload starts downloading filePath from offset on. The caller must
+// check the status code: a server may answer 200 with the whole file instead of 206.
+func (client *HuggingFaceClient) openDownload(ctx context.Context, repoType, repoName, revision, filePath string, offset int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", client.resolveURL(repoType, repoName, revision, filePath), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create download request: %w", err)
+	}
+	if err := client.authorize(req); err != nil {
+		return nil, err
+	}
+	// A compressed answer would have neither the size nor the content that
+	// the ETag is the checksum of
+	req.Header.Set("Accept-Encoding", "identity")
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	return client.doRequest(req)
+}
+
+// DownloadToWriter streams filePath into w and returns the number of bytes written.
+// The content is checked against the checksum in the ETag of the Hub's answer
+// once written; a *ChecksumError means w received something else.
+func (client *HuggingFaceClient) DownloadToWriter(repoType, repoName, revision, filePath string, w io.Writer) (int64, error) {
+	return client.DownloadToWriterContext(context.Background(), repoType, repoName, revision, filePath, w)
+}
+
+func (client *HuggingFaceClient) DownloadToWriterContext(ctx context.Context, repoType, repoName, revision, filePath string, w io.Writer) (int64, error) {
+	resp, err := client.openDownload(ctx, repoType, repoName, revision, filePath, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to download file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	want := responseChecksum(resp)
+	var h hash.Hash
+	if want != nil {
+		h = want.newHash()
+		w = io.MultiWriter(w, h)
+	}
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return n, fmt.Errorf("failed to download file: %w", err)
+	}
+	if want != nil {
+		return n, want.check(filePath, h)
+	}
+	return n, nil
+}
+
+// DownloadToPath downloads filePath to dest through dest+IncompleteSuffix,
+// resuming a previous attempt with a Range request, and renames it once complete.
+// A connection dropped mid-transfer is resumed according to the retry policy.
+// With WithChunkedDownload, big LFS files are fetched in parallel chunks instead.
+// The file is checked against the checksum in the ETag of the Hub's answer
+// before the rename; on a mismatch it is removed and a *ChecksumError returned.
+func (client *HuggingFaceClient) DownloadToPath(repoType, repoName, revision, filePath, dest string) error {
+	return client.DownloadToPathContext(context.Background(), repoType, repoName, revision, filePath, dest)
+}
+
+func (client *HuggingFaceClient) DownloadToPathContext(ctx context.Context, repoType, repoName, revision, filePath, dest string) error {
+	var want *checksum
+	if client.ChunkConnections > 1 {
+		// Whether to download in chunks depends on the size of the file
+		meta, err := client.getFileMetadata(ctx, repoType, repoName, revision, filePath)
+		if err != nil {
+			return fmt.Errorf("failed to download file: %w", err)
+		}
+		want = etagChecksum(meta.ETag, meta.Size)
+		// Every chunk must come from the same commit, whatever is pushed
+		// to the branch meanwhile
+		revision = meta.CommitHash
+	}
+	return client.downloadToPath(ctx, repoType, repoName, revision, filePath, dest, want)
+}
+
+// downloadToPath is DownloadToPath with the checksum of the file already
+// known; nil takes it from the answer of the Hub.
+func (client *HuggingFaceClient) downloadToPath(ctx context.Context, repoType, repoName, revision, filePath, dest string, want *checksum) error {
+	if chunked, err := client.downloadChunkedToPath(ctx, repoType, repoName, revision, filePath, dest, want); chunked {
+		return err
+	}
+
+	tmp := dest + IncompleteSuffix
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmp, err)
+	}
+
+	var got *checksum
+	policy := client.retryPolicy()
+	for attempt := 1; ; attempt++ {
+		got, err = client.resumeDownload(ctx, repoType, repoName, revision, filePath, f)
+		if err == nil || ctx.Err() != nil || attempt >= policy.MaxAttempts {
+			break
+		}
+		var hubErr *HubError
+		if errors.As(err, &hubErr) {
+			// The Hub answered; doRequest already retried what was worth retrying
+			break
+		}
+
+		wait := policy.backoff(attempt, err)
+		if policy.OnRetry != nil {
+			policy.OnRetry(RetryEvent{
+				Method:  "GET",
+				URL:     client.resolveURL(repoType, repoName, revision, filePath),
+				Attempt: attempt,
+				Wait:    wait,
+				Err:     err,
+			})
+		}
+		if err = sleepContext(ctx, wait); err != nil {
+			break
+		}
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download file: %w", err)
+	}
+	if want == nil {
+		want = got
+	}
+	if want != nil {
+		if err := want.verifyFile(filePath, tmp); err != nil {
+			// Resuming from a corrupted file would not help
+			os.Remove(tmp)
+			os.Remove(tmp + ETagSuffix)
+			return err
+		}
+	}
+	os.Remove(tmp + ETagSuffix)
+	return os.Rename(tmp, dest)
+}
+
+// resumeDownload appends the missing part of filePath to f, and returns the
+// checksum the Hub gave for the file. The ETag of the file is saved next to
+// f, see ETagSuffix, and a partial file is only resumed if the file on the
+// Hub still has that ETag.
+func (client *HuggingFaceClient) resumeDownload(ctx context.Context, repoType, repoName, revision, filePath string, f *os.File) (*checksum, error) {
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	etagPath := f.Name() + ETagSuffix
+	savedETag := ""
+	if offset > 0 {
+		saved, err := os.ReadFile(etagPath)
+		if err == nil {
+			savedETag = strings.TrimSpace(string(saved))
+		}
+		if savedETag == "" {
+			// No way to tell which version the partial file is from
+			offset = 0
+		}
+	}
+
+	resp, err := client.openDownload(ctx, repoType, repoName, revision, filePath, offset)
+	var hubErr *HubError
+	if offset > 0 && errors.As(err, &hubErr) && hubErr.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		// Nothing left to download if the partial file is already complete
+		meta, metaErr := client.getFileMetadata(ctx, repoType, repoName, revision, filePath)
+		if metaErr == nil && meta.ETag == savedETag && meta.Size == offset {
+			return etagChecksum(meta.ETag, meta.Size), nil
+		}
+		offset = 0
+		resp, err = client.openDownload(ctx, repoType, repoName, revision, filePath, 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if etag := responseETag(resp); offset > 0 && resp.StatusCode == http.StatusPartialContent && etag != savedETag {
+		// The partial file is from another version of the file; start over
+		resp.Body.Close()
+		offset = 0
+		if resp, err = client.openDownload(ctx, repoType, repoName, revision, filePath, 0); err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent && offset > 0 {
+		// Range ignored, the whole file is coming
+		offset = 0
+	}
+	if offset == 0 {
+		if etag := responseETag(resp); etag != "" {
+			if err := os.WriteFile(etagPath, []byte(etag), 0644); err != nil {
+				return nil, err
+			}
+		} else {
+			os.Remove(etagPath)
+		}
+	}
+	if err := f.Truncate(offset); err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var total int64
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+	var w io.Writer = f
+	if client.Progress != nil {
+		client.Progress(filePath, offset, total)
+		w = &progressWriter{w: f, n: offset, report: func(n int64) {
+			client.Progress(filePath, n, total)
+		}}
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	return responseChecksum(resp), err
+}
+
+// responseETag returns the ETag of the file a resolve request got: the
+// X-Linked-Etag of the Hub's redirect to the CDN for LFS files, the ETag of
+// the answer otherwise.
+func responseETag(resp *http.Response) string {
+	if etag := linkedHeader(resp, "X-Linked-Etag"); etag != "" {
+		return normalizeETag(etag)
+	}
+	return normalizeETag(resp.Header.Get("ETag"))
+}
+
+// responseChecksum returns what the file a resolve request got must hash
+// to, from its ETag and size, or nil if the answer does not tell.
+func responseChecksum(resp *http.Response) *checksum {
+	size := resp.ContentLength
+	if resp.StatusCode == http.StatusPartialContent {
+		// Content-Range: bytes first-last/total
+		size = -1
+		if i := strings.LastIndexByte(resp.Header.Get("Content-Range"), '/'); i >= 0 {
+			size, _ = strconv.ParseInt(resp.Header.Get("Content-Range")[i+1:], 10, 64)
+		}
+	}
+	if linked, err := strconv.ParseInt(linkedHeader(resp, "X-Linked-Size"), 10, 64); err == nil {
+		size = linked
+	}
+
+	want := etagChecksum(responseETag(resp), size)
+	if want != nil && want.algo == "sha1" && size <= 0 {
+		// The git blob hash covers the size, which is unknown
+		return nil
+	}
+	return want
+}
+
+// linkedHeader returns the header key of resp or of the redirects that led
+// to it, which is where the Hub puts the X-Linked-* headers of LFS files.
+func linkedHeader(resp *http.Response, key string) string {
+	for r := resp; r != nil; {
+		if value := r.Header.Get(key); value != "" {
+			return value
+		}
+		if r.Request == nil {
+			break
+		}
+		r = r.Request.Response
+	}
+	return ""
+}
+
+func normalizeETag(etag string) string {
+	return strings.Trim(strings.TrimPrefix(etag, "W/"), `"`)
+}
@@ -0,0 +1,95 @@
+package apiv2
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fileServer serves content as the file data.bin of the model user/repo,
+// with etag as its ETag, and records the path and Range header of every GET.
+type fileServer struct {
+	*httptest.Server
+	content []byte
+	etag    string
+
+	mu     sync.Mutex
+	paths  []string
+	ranges []string
+}
+
+func newFileServer(t *testing.T, content []byte, etag string) *fileServer {
+	fs := &fileServer{content: content, etag: etag}
+	fs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/models/user/repo/resolve/") || !strings.HasSuffix(r.URL.Path, "/data.bin") {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == "GET" {
+			fs.mu.Lock()
+			fs.paths = append(fs.paths, r.URL.Path)
+			fs.ranges = append(fs.ranges, r.Header.Get("Range"))
+			fs.mu.Unlock()
+		}
+		w.Header().Set("ETag", `"`+fs.etag+`"`)
+		w.Header().Set("X-Repo-Commit", strings.Repeat("c", 40))
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(fs.content))
+	}))
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+func TestDownloadToPathResume(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 100))
+	etag := "v2"
+
+	tests := []struct {
+		name       string
+		partial    []byte
+		savedETag  string
+		wantRanges []string
+	}{
+		{"fresh download", nil, "", []string{""}},
+		{"valid prefix resumed", content[:400], etag, []string{"bytes=400-"}},
+		{"no saved ETag starts over", content[:400], "", []string{""}},
+		{"stale ETag starts over", []byte("other version"), "v1", []string{"bytes=13-", ""}},
+		{"complete file kept", content, etag, []string{"bytes=1000-"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFileServer(t, content, etag)
+			dest := filepath.Join(t.TempDir(), "data.bin")
+			tmp := dest + IncompleteSuffix
+			if tt.partial != nil {
+				if err := os.WriteFile(tmp, tt.partial, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.savedETag != "" {
+				if err := os.WriteFile(tmp+ETagSuffix, []byte(tt.savedETag), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			client := NewHuggingFaceClient("", WithEndpoint(server.URL))
+			if err := client.DownloadToPath("model", "user/repo", "", "data.bin", dest); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := os.ReadFile(dest); !bytes.Equal(got, content) {
+				t.Errorf("downloaded %d bytes, want the %d bytes of the file", len(got), len(content))
+			}
+			if fileExists(tmp) || fileExists(tmp+ETagSuffix) {
+				t.Errorf("%s or its ETag file is left behind", tmp)
+			}
+			if strings.Join(server.ranges, ",") != strings.Join(tt.wantRanges, ",") {
+				t.Errorf("Range headers = %q, want %q", server.ranges, tt.wantRanges)
+			}
+		})
+	}
+}
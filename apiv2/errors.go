@@ -0,0 +1,125 @@
+package apiv2
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Error categories of the Hub. Use errors.Is to check which one a HubError belongs to.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrGated        = errors.New("gated repository")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrConflict     = errors.New("conflict")
+
+	// ErrChecksumMismatch is the category of ChecksumError.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+)
+
+// HubError is returned for every non-2xx response of the Hub.
+type HubError struct {
+	StatusCode int
+	Method     string
+	URL        string
+	// RequestID comes from the X-Request-Id header; include it in bug reports to Hugging Face.
+	RequestID string
+	// Code is the X-Error-Code header, e.g. RepoNotFound or GatedRepo.
+	Code string
+	// Message is the error reported by the Hub, or the raw response body.
+	Message string
+	// RetryAfter is the wait requested by the Retry-After header, if any.
+	RetryAfter time.Duration
+}
+
+func (e *HubError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	msg = RedactToken(msg)
+	if e.RequestID != "" {
+		return fmt.Sprintf("%d %s (request id: %s)", e.StatusCode, msg, e.RequestID)
+	}
+	return fmt.Sprintf("%d %s", e.StatusCode, msg)
+}
+
+// Unwrap returns the category of the error, so errors.Is(err, ErrNotFound) works.
+func (e *HubError) Unwrap() error {
+	return e.Category()
+}
+
+// Category returns one of the Err* sentinels, or nil if the error fits none of them.
+func (e *HubError) Category() error {
+	switch e.Code {
+	case "RepoNotFound", "EntryNotFound", "RevisionNotFound":
+		return ErrNotFound
+	case "GatedRepo":
+		return ErrGated
+	}
+	switch e.StatusCode {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		if strings.Contains(strings.ToLower(e.Message), "gated") {
+			return ErrGated
+		}
+		return ErrUnauthorized
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusConflict:
+		return ErrConflict
+	}
+	return nil
+}
+
+// ChecksumError is returned when a file does not hash to what the Hub says it should.
+type ChecksumError struct {
+	Path string
+	// Algorithm is "sha256" for LFS files and "sha1" (git blob hash) for the others.
+	Algorithm string
+	Expected  string
+	Actual    string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("checksum mismatch for %s: expected %s %s, got %s", e.Path, e.Algorithm, e.Expected, e.Actual)
+}
+
+// Unwrap returns ErrChecksumMismatch.
+func (e *ChecksumError) Unwrap() error {
+	return ErrChecksumMismatch
+}
+
+// newHubError builds a HubError from a failed response and consumes its body.
+func newHubError(resp *http.Response) *HubError {
+	hubErr := &HubError{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("X-Request-Id"),
+		Code:       resp.Header.Get("X-Error-Code"),
+		Message:    resp.Header.Get("X-Error-Message"),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+	if resp.Request != nil {
+		hubErr.Method = resp.Request.Method
+		hubErr.URL = resp.Request.URL.String()
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	var payload struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil && payload.Error != "" {
+		hubErr.Message = payload.Error
+	} else if hubErr.Message == "" {
+		hubErr.Message = strings.TrimSpace(string(body))
+	}
+	return hubErr
+}
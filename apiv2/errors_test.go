@@ -0,0 +1,80 @@
+package apiv2
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHubErrorCategory(t *testing.T) {
+	tests := []struct {
+		err  HubError
+		want error
+	}{
+		{HubError{StatusCode: 404}, ErrNotFound},
+		{HubError{StatusCode: 401, Code: "RepoNotFound"}, ErrNotFound},
+		{HubError{StatusCode: 400, Code: "RevisionNotFound"}, ErrNotFound},
+		{HubError{StatusCode: 401, Code: "GatedRepo"}, ErrGated},
+		{HubError{StatusCode: 401}, ErrUnauthorized},
+		{HubError{StatusCode: 403, Message: "Access to this Gated model is restricted"}, ErrGated},
+		{HubError{StatusCode: 403, Message: "forbidden"}, ErrUnauthorized},
+		{HubError{StatusCode: 429}, ErrRateLimited},
+		{HubError{StatusCode: 409}, ErrConflict},
+		{HubError{StatusCode: 500}, nil},
+		{HubError{StatusCode: 400}, nil},
+	}
+	for _, tt := range tests {
+		err := tt.err
+		if got := err.Category(); got != tt.want {
+			t.Errorf("Category of %d %q = %v, want %v", err.StatusCode, err.Code, got, tt.want)
+		}
+		if tt.want != nil && !errors.Is(&err, tt.want) {
+			t.Errorf("errors.Is(%d %q, %v) = false", err.StatusCode, err.Code, tt.want)
+		}
+	}
+}
+
+func TestNewHubError(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      map[string]string
+		body        string
+		wantMessage string
+	}{
+		{"json error", nil, `{"error":"Repository not found"}`, "Repository not found"},
+		{"header message", map[string]string{"X-Error-Message": "Invalid token"}, "", "Invalid token"},
+		{"raw body", nil, "  bad gateway \n", "bad gateway"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Request-Id", "req-1")
+				w.Header().Set("X-Error-Code", "RepoNotFound")
+				w.Header().Set("Retry-After", "3")
+				for key, value := range tt.header {
+					w.Header().Set(key, value)
+				}
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			resp, err := http.Get(server.URL + "/api/models/x")
+			if err != nil {
+				t.Fatal(err)
+			}
+			hubErr := newHubError(resp)
+			resp.Body.Close()
+			if hubErr.Message != tt.wantMessage || hubErr.RequestID != "req-1" || hubErr.Code != "RepoNotFound" ||
+				hubErr.RetryAfter != 3*time.Second || hubErr.Method != "GET" || !strings.HasSuffix(hubErr.URL, "/api/models/x") {
+				t.Errorf("newHubError = %+v", hubErr)
+			}
+			if !strings.Contains(hubErr.Error(), "(request id: req-1)") {
+				t.Errorf("Error() = %q, want the request id", hubErr.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package apiv2
+
+import "os"
+
+// lockFile does nothing where files cannot be locked.
+func lockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,20 @@
+//go:build unix
+
+package apiv2
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// lockFile blocks until it holds an exclusive lock on f, which is released
+// when f is closed.
+func lockFile(f *os.File) error {
+	for {
+		err := unix.Flock(int(f.Fd()), unix.LOCK_EX)
+		if err != unix.EINTR {
+			return err
+		}
+	}
+}
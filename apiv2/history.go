@@ -0,0 +1,292 @@
+package apiv2
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HFCommit is a commit of the history of a repository.
+type HFCommit struct {
+	ID      string           `json:"id"`
+	Title   string           `json:"title"`
+	Message string           `json:"message"`
+	Authors []HFCommitAuthor `json:"authors"`
+	Date    time.Time        `json:"date"`
+}
+
+// HFCommitAuthor is a Hub user who authored a commit.
+type HFCommitAuthor struct {
+	User string `json:"user"`
+}
+
+// AuthorNames joins the names of the authors of c.
+func (c *HFCommit) AuthorNames() string {
+	names := make([]string, len(c.Authors))
+	for i, author := range c.Authors {
+		names[i] = author.User
+	}
+	return strings.Join(names, ", ")
+}
+
+// CommitFile is a file touched by a commit. Status is "added", "modified",
+// "deleted" or "renamed", in which case OldPath is the path before.
+type CommitFile struct {
+	Path    string `json:"path"`
+	Status  string `json:"status"`
+	OldPath string `json:"oldPath,omitempty"`
+}
+
+// CommitDetail is a commit and the files it touched.
+type CommitDetail struct {
+	HFCommit
+	Files []CommitFile `json:"files"`
+}
+
+// commitListing is the output of log.
+type commitListing []HFCommit
+
+func (commits commitListing) csvHeader() []string {
+	return []string{"id", "date", "authors", "title"}
+}
+
+func (commits commitListing) csvRows() [][]string {
+	rows := make([][]string, len(commits))
+	for i, c := range commits {
+		rows[i] = []string{c.ID, c.Date.Format(time.RFC3339), c.AuthorNames(), c.Title}
+	}
+	return rows
+}
+
+func (detail *CommitDetail) csvHeader() []string {
+	return []string{"id", "path", "status", "oldPath"}
+}
+
+func (detail *CommitDetail) csvRows() [][]string {
+	rows := make([][]string, len(detail.Files))
+	for i, f := range detail.Files {
+		rows[i] = []string{detail.ID, f.Path, f.Status, f.OldPath}
+	}
+	return rows
+}
+
+// maxPathCommits is how many of the newest commits ListCommits looks at
+// when it filters them by path.
+const maxPathCommits = 1000
+
+// CommitListOptions narrow down ListCommits.
+type CommitListOptions struct {
+	// Path keeps only the commits that changed this file, or a file under
+	// this folder. The Hub cannot filter commits, so the tree of every commit
+	// is looked up with one more request, for the newest 1000 commits at most.
+	Path string
+	// Limit stops the listing after that many commits; 0 lists them all.
+	Limit int
+}
+
+func (client *HuggingFaceClient) ListCommits(repoType, repoID, revision string, opts CommitListOptions) ([]HFCommit, error) {
+	return client.ListCommitsContext(context.Background(), repoType, repoID, revision, opts)
+}
+
+// ListCommitsContext returns the history of revision, newest first,
+// following the Link header from page to page.
+func (client *HuggingFaceClient) ListCommitsContext(ctx context.Context, repoType, repoID, revision string, opts CommitListOptions) ([]HFCommit, error) {
+	endpoint := fmt.Sprintf("%s/api/%s/%s/commits/%s", client.endpoint(), repoType+"s", repoID, escapeRevision(revision))
+	path := strings.Trim(opts.Path, "/")
+	full := func(commits []HFCommit) bool {
+		return opts.Limit > 0 && len(commits) >= opts.Limit
+	}
+
+	commits := []HFCommit{}
+	// With a path, a commit changed it if the path is not the same as in the
+	// commit before, which is only known once the next commit is listed
+	var last *HFCommit
+	var lastOid string
+	scanned := 0
+	for endpoint != "" && !full(commits) {
+		page, next, err := client.commitsPage(ctx, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		endpoint = next
+
+		if path == "" {
+			for _, c := range page {
+				if full(commits) {
+					break
+				}
+				commits = append(commits, c)
+			}
+			continue
+		}
+
+		if len(page) > maxPathCommits-scanned {
+			page, endpoint = page[:maxPathCommits-scanned], ""
+		}
+		scanned += len(page)
+		oids, err := client.pathOids(ctx, repoType, repoID, path, page)
+		if err != nil {
+			return nil, err
+		}
+		for i := range page {
+			if last != nil && lastOid != oids[i] && !full(commits) {
+				commits = append(commits, *last)
+			}
+			last, lastOid = &page[i], oids[i]
+		}
+		if endpoint == "" && last != nil && lastOid != "" && !full(commits) {
+			// The path is in the oldest commit looked at, which added it
+			// unless the history goes on past maxPathCommits
+			if scanned < maxPathCommits {
+				commits = append(commits, *last)
+			}
+		}
+	}
+	return commits, nil
+}
+
+// pathOids returns the oid of path, a file or a folder, in each commit of
+// page, or "" where it does not exist.
+func (client *HuggingFaceClient) pathOids(ctx context.Context, repoType, repoID, path string, page []HFCommit) ([]string, error) {
+	ids := make([]string, len(page))
+	for i, c := range page {
+		ids[i] = c.ID
+	}
+	oids := make([]string, len(page))
+	err := forEachParallel(ctx, client.concurrency(), ids, func(i int, id string) error {
+		infos, err := client.pathsInfo(ctx, repoType, repoID, id, []string{path})
+		for _, info := range infos {
+			if info.Path == path {
+				oids[i] = info.Oid
+			}
+		}
+		return err
+	})
+	var transferErr *TransferError
+	if errors.As(err, &transferErr) {
+		f := transferErr.Failures[0]
+		return nil, fmt.Errorf("failed to look up %s in commit %s: %w", path, f.Path, f.Err)
+	}
+	return oids, err
+}
+
+func (client *HuggingFaceClient) commitsPage(ctx context.Context, endpoint string) ([]HFCommit, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create commits request: %w", err)
+	}
+	if err := client.authorizePage(req); err != nil {
+		return nil, "", err
+	}
+
+	resp, err := client.doRequest(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	var page []HFCommit
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, "", fmt.Errorf("failed to decode commits: %w", err)
+	}
+	return page, nextPageURL(resp.Header), nil
+}
+
+func (client *HuggingFaceClient) GetCommit(repoType, repoID, commit string) (*CommitDetail, error) {
+	return client.GetCommitContext(context.Background(), repoType, repoID, commit)
+}
+
+// GetCommitContext returns a commit, or the head of a branch or tag, with
+// the files it touched.
+func (client *HuggingFaceClient) GetCommitContext(ctx context.Context, repoType, repoID, commit string) (*CommitDetail, error) {
+	endpoint := fmt.Sprintf("%s/api/%s/%s/commits/%s", client.endpoint(), repoType+"s", repoID, escapeRevision(commit))
+	page, _, err := client.commitsPage(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if len(page) == 0 {
+		return nil, fmt.Errorf("commit %s: %w", commit, ErrNotFound)
+	}
+
+	files, err := client.commitFiles(ctx, repoType, repoID, page[0].ID)
+	if err != nil {
+		return nil, err
+	}
+	return &CommitDetail{HFCommit: page[0], Files: files}, nil
+}
+
+// commitFiles lists the files touched by a commit, from its diff.
+func (client *HuggingFaceClient) commitFiles(ctx context.Context, repoType, repoID, commit string) ([]CommitFile, error) {
+	url := fmt.Sprintf("%s/%s%s/commit/%s.diff", client.endpoint(), lfsRepoPrefix(repoType), repoID, commit)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create diff request: %w", err)
+	}
+	if err := client.authorize(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := client.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	files := []CommitFile{}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		last := len(files) - 1
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			files = append(files, CommitFile{Path: diffPath(strings.TrimPrefix(line, "diff --git ")), Status: "modified"})
+		case last < 0:
+		case strings.HasPrefix(line, "new file mode"):
+			files[last].Status = "added"
+		case strings.HasPrefix(line, "deleted file mode"):
+			files[last].Status = "deleted"
+		case strings.HasPrefix(line, "rename from "):
+			files[last].Status = "renamed"
+			files[last].OldPath = unquoteDiffPath(strings.TrimPrefix(line, "rename from "))
+		case strings.HasPrefix(line, "rename to "):
+			files[last].Path = unquoteDiffPath(strings.TrimPrefix(line, "rename to "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read diff of %s: %w", commit, err)
+	}
+	return files, nil
+}
+
+// diffPath returns the path of a "diff --git a/path b/path" header. Renames
+// have different paths, which the "rename to" line that follows gives.
+func diffPath(header string) string {
+	if strings.HasPrefix(header, `"`) {
+		// Quoted paths: "a/some path" "b/some path"
+		if end := strings.Index(header[1:], `" `); end >= 0 {
+			return strings.TrimPrefix(unquoteDiffPath(header[:end+2]), "a/")
+		}
+	}
+	if half := (len(header) - 1) / 2; len(header)%2 == 1 && header[half] == ' ' {
+		return strings.TrimPrefix(header[:half], "a/")
+	}
+	if i := strings.Index(header, " b/"); i >= 0 {
+		return strings.TrimPrefix(header[:i], "a/")
+	}
+	return header
+}
+
+// unquoteDiffPath decodes a path that git quoted because of special characters.
+func unquoteDiffPath(path string) string {
+	if unquoted, err := strconv.Unquote(path); err == nil {
+		return unquoted
+	}
+	return path
+}
@@ -0,0 +1,102 @@
+package apiv2
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffPath(t *testing.T) {
+	tests := []struct {
+		header, want string
+	}{
+		{"a/config.json b/config.json", "config.json"},
+		{"a/dir/with space.txt b/dir/with space.txt", "dir/with space.txt"},
+		{`"a/caf\303\251.txt" "b/caf\303\251.txt"`, "café.txt"},
+		{"a/old.txt b/new.txt", "old.txt"},
+	}
+	for _, tt := range tests {
+		if got := diffPath(tt.header); got != tt.want {
+			t.Errorf("diffPath(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+// historyServer serves a history of commits c5 (newest) to c1 in pages of
+// two, and the oid of data/a.csv in each of them.
+func historyServer(t *testing.T, oids map[string]string) *httptest.Server {
+	commits := []string{"c5", "c4", "c3", "c2", "c1"}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/api/models/user/repo/commits/"):
+			page := 0
+			fmt.Sscan(r.URL.Query().Get("p"), &page)
+			end := 2 * (page + 1)
+			if end >= len(commits) {
+				end = len(commits)
+			} else {
+				w.Header().Set("Link", fmt.Sprintf(`<%s%s?p=%d>; rel="next"`, server.URL, r.URL.Path, page+1))
+			}
+			var list []HFCommit
+			for _, id := range commits[2*page : end] {
+				list = append(list, HFCommit{ID: id, Title: "commit " + id})
+			}
+			json.NewEncoder(w).Encode(list)
+		case strings.HasPrefix(r.URL.Path, "/api/models/user/repo/paths-info/"):
+			commit := strings.TrimPrefix(r.URL.Path, "/api/models/user/repo/paths-info/")
+			r.ParseForm()
+			if got := r.Form["paths"]; !reflect.DeepEqual(got, []string{"data/a.csv"}) {
+				t.Errorf("paths-info paths = %q, want data/a.csv", got)
+			}
+			files := []HFFile{}
+			if oid := oids[commit]; oid != "" {
+				files = append(files, HFFile{Type: "file", Path: "data/a.csv", Oid: oid})
+			}
+			json.NewEncoder(w).Encode(files)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return server
+}
+
+func TestListCommitsPath(t *testing.T) {
+	// data/a.csv was added by c2, changed by c4, unchanged by c3 and c5
+	changed := map[string]string{"c5": "v2", "c4": "v2", "c3": "v1", "c2": "v1"}
+	// data/a.csv was added by c4 and deleted by c5
+	deleted := map[string]string{"c4": "v1"}
+
+	tests := []struct {
+		oids map[string]string
+		opts CommitListOptions
+		want []string
+	}{
+		{changed, CommitListOptions{}, []string{"c5", "c4", "c3", "c2", "c1"}},
+		{changed, CommitListOptions{Limit: 3}, []string{"c5", "c4", "c3"}},
+		{changed, CommitListOptions{Path: "data/a.csv"}, []string{"c4", "c2"}},
+		{changed, CommitListOptions{Path: "/data/a.csv/", Limit: 1}, []string{"c4"}},
+		{deleted, CommitListOptions{Path: "data/a.csv"}, []string{"c5", "c4"}},
+		{map[string]string{}, CommitListOptions{Path: "data/a.csv"}, []string{}},
+	}
+	for _, tt := range tests {
+		server := historyServer(t, tt.oids)
+		client := NewHuggingFaceClient("", WithEndpoint(server.URL))
+		commits, err := client.ListCommits("model", "user/repo", "", tt.opts)
+		server.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, c := range commits {
+			got = append(got, c.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ListCommits(%+v) with oids %v = %q, want %q", tt.opts, tt.oids, got, tt.want)
+		}
+	}
+}
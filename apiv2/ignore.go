@@ -0,0 +1,217 @@
+package apiv2
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFileName is the file that lists, in gitignore syntax, the files of a
+// folder that upload leaves out.
+const IgnoreFileName = ".huggerignore"
+
+// IgnoreRules are the rules of the ignore files of a folder tree. As in git,
+// the last rule that matches a path decides, and nothing inside an ignored
+// folder can be brought back.
+type IgnoreRules struct {
+	rules []ignoreRule
+}
+
+type ignoreRule struct {
+	// base is the folder of the ignore file, relative to the root of the
+	// tree, or "" for the root.
+	base    string
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ListLocalFiles returns the slash-separated paths, relative to root, of the
+// files below root that an upload takes: all but those of the .git folder and
+// those the ignore files of root and its subfolders ignore. It also returns
+// the rules of these ignore files. An ignore file that cannot be read has no
+// rule and is passed to warn, if not nil.
+func ListLocalFiles(root string, warn func(error)) ([]string, *IgnoreRules, error) {
+	var files []string
+	ignore := &IgnoreRules{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if d.Name() == ".git" || (rel != "." && ignore.Ignored(rel, true)) {
+				return filepath.SkipDir
+			}
+			base := rel
+			if base == "." {
+				base = ""
+			}
+			if err := ignore.AddFile(filepath.Join(path, IgnoreFileName), base); err != nil && warn != nil {
+				warn(err)
+			}
+			return nil
+		}
+		if !ignore.Ignored(rel, false) {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list %s: %w", root, err)
+	}
+	return files, ignore, nil
+}
+
+// AddFile reads the ignore file at path, which sits in the folder base of the
+// tree. A missing file has no rule.
+func (ig *IgnoreRules) AddFile(path, base string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer f.Close()
+	if err := ig.Parse(f, base); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return nil
+}
+
+// Parse adds the rules of an ignore file in the folder base of the tree,
+// slash-separated and "" for the root.
+func (ig *IgnoreRules) Parse(r io.Reader, base string) error {
+	base = strings.Trim(base, "/")
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+			line = line[:len(line)-1]
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate, line = true, line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly, line = true, strings.TrimSuffix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		// A slash other than a trailing one anchors the pattern to base,
+		// otherwise it matches a name at any depth.
+		prefix := "(?:.*/)?"
+		if strings.Contains(line, "/") {
+			prefix, line = "", strings.TrimPrefix(line, "/")
+		}
+		re, err := regexp.Compile("^" + prefix + translateIgnorePattern(line) + "$")
+		if err != nil {
+			continue
+		}
+		rule.re = re
+		ig.rules = append(ig.rules, rule)
+	}
+	return scanner.Err()
+}
+
+// translateIgnorePattern turns a gitignore pattern into a regular
+// expression: '*' and '?' stop at '/', "**/" matches any number of folders
+// and a trailing "/**" everything inside a folder.
+func translateIgnorePattern(pattern string) string {
+	var sb strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if !strings.HasPrefix(pattern[i:], "**") {
+				sb.WriteString("[^/]*")
+				continue
+			}
+			atStart := i == 0 || pattern[i-1] == '/'
+			switch {
+			case atStart && strings.HasPrefix(pattern[i:], "**/"):
+				sb.WriteString("(?:.*/)?")
+				i += 2
+			case atStart && i+2 == len(pattern):
+				sb.WriteString(".*")
+				i++
+			default:
+				sb.WriteString("[^/]*")
+				i++
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+			sb.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
+
+// Ignored reports whether path, slash-separated and relative to the root of
+// the tree, is ignored, either itself or because a folder above it is.
+// isDir tells whether path is a folder.
+func (ig *IgnoreRules) Ignored(path string, isDir bool) bool {
+	if ig == nil || len(ig.rules) == 0 {
+		return false
+	}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 1; i < len(parts); i++ {
+		if ig.match(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return ig.match(strings.Join(parts, "/"), isDir)
+}
+
+func (ig *IgnoreRules) match(path string, isDir bool) bool {
+	ignored := false
+	for _, rule := range ig.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		rel := path
+		if rule.base != "" {
+			if !strings.HasPrefix(path, rule.base+"/") {
+				continue
+			}
+			rel = path[len(rule.base)+1:]
+		}
+		if rule.re.MatchString(rel) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,124 @@
+package apiv2
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestTranslateIgnorePattern(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"*.log", "debug.log", true},
+		{"*.log", "logs/debug.log", false},
+		{"data?.csv", "data1.csv", true},
+		{"data?.csv", "data/.csv", false},
+		{"**/cache", "cache", true},
+		{"**/cache", "a/b/cache", true},
+		{"logs/**", "logs/a/b.txt", true},
+		{"logs/**", "logs", false},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"a**b", "axxb", true},
+		{"a**b", "ax/xb", false},
+		{"[abc].txt", "b.txt", true},
+		{"[!abc].txt", "b.txt", false},
+		{`\#notes`, "#notes", true},
+		{"file.txt", "fileXtxt", false},
+	}
+	for _, tt := range tests {
+		re := "^" + translateIgnorePattern(tt.pattern) + "$"
+		rules := &IgnoreRules{}
+		if err := rules.Parse(strings.NewReader("/"+tt.pattern), ""); err != nil {
+			t.Fatal(err)
+		}
+		if got := rules.match(tt.path, false); got != tt.want {
+			t.Errorf("pattern %q (%s) on %q = %v, want %v", tt.pattern, re, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestIgnoreRules(t *testing.T) {
+	rules := &IgnoreRules{}
+	root := "# comment\n*.tmp\n!keep.tmp\nbuild/\n/secret.txt\ntrailing \n"
+	if err := rules.Parse(strings.NewReader(root), ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := rules.Parse(strings.NewReader("*.csv\n!raw/\n"), "data"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"a.tmp", false, true},
+		{"sub/a.tmp", false, true},
+		{"keep.tmp", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"build/out.bin", false, true},
+		{"sub/build/out.bin", false, true},
+		{"secret.txt", false, true},
+		{"sub/secret.txt", false, false},
+		{"trailing", false, true},
+		{"# comment", false, false},
+		{"data/a.csv", false, true},
+		{"data/sub/a.csv", false, true},
+		{"a.csv", false, false},
+		{"other/data/a.csv", false, false},
+		{"model.safetensors", false, false},
+	}
+	for _, tt := range tests {
+		if got := rules.Ignored(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Ignored(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+
+	var none *IgnoreRules
+	if none.Ignored("a.tmp", false) {
+		t.Errorf("nil IgnoreRules ignored a.tmp")
+	}
+}
+
+func TestListLocalFiles(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".huggerignore":      "*.log\nbuild/\n",
+		"model.safetensors":  "weights",
+		"debug.log":          "log",
+		"build/out.bin":      "out",
+		".git/HEAD":          "ref",
+		"data/.huggerignore": "!keep.log\n",
+		"data/keep.log":      "kept",
+		"data/train.csv":     "a,b",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, rules, err := ListLocalFiles(root, func(err error) { t.Errorf("warning: %v", err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	want := []string{".huggerignore", "data/.huggerignore", "data/keep.log", "data/train.csv", "model.safetensors"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListLocalFiles = %q, want %q", got, want)
+	}
+	if !rules.Ignored("debug.log", false) || rules.Ignored("data/keep.log", false) {
+		t.Errorf("the rules returned by ListLocalFiles are not those of the ignore files")
+	}
+}
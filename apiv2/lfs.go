@@ -0,0 +1,278 @@
+package apiv2
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync/atomic"
+)
+
+const (
+	lfsMediaType = "application/vnd.git-lfs+json"
+	// sampleSize is how much of a file the preupload endpoint needs to pick an upload mode.
+	sampleSize = 512
+)
+
+// uploadSource is the content of a file to upload. It is read through
+// io.ReaderAt so that multi-GB files never have to sit in memory.
+type uploadSource struct {
+	r    io.ReaderAt
+	size int64
+
+	// progress, when set, is told about the bytes sent to LFS storage.
+	progress func(delta int64)
+}
+
+func (src uploadSource) section(offset, length int64) *io.SectionReader {
+	return io.NewSectionReader(src.r, offset, length)
+}
+
+func (src uploadSource) sample() ([]byte, error) {
+	n := src.size
+	if n > sampleSize {
+		n = sampleSize
+	}
+	return ioutil.ReadAll(src.section(0, n))
+}
+
+func (src uploadSource) sha256() (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, src.section(0, src.size)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+type lfsAction struct {
+	Href   string            `json:"href"`
+	Header map[string]string `json:"header"`
+}
+
+type lfsObject struct {
+	Oid     string `json:"oid"`
+	Size    int64  `json:"size"`
+	Actions struct {
+		Upload *lfsAction `json:"upload"`
+		Verify *lfsAction `json:"verify"`
+	} `json:"actions"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type lfsBatchResponse struct {
+	Transfer string      `json:"transfer"`
+	Objects  []lfsObject `json:"objects"`
+}
+
+// lfsRepoPrefix is the URL prefix of a repository in git URLs, where models have none.
+func lfsRepoPrefix(repoType string) string {
+	if repoType == "" || repoType == "model" {
+		return ""
+	}
+	return repoType + "s/"
+}
+
+// lfsBatch asks the Hub where to upload an LFS object with the given sha256.
+func (client *HuggingFaceClient) lfsBatch(ctx context.Context, repoType, repoID, revision, oid string, size int64) (*lfsObject, string, error) {
+	url := fmt.Sprintf("%s/%s%s.git/info/lfs/objects/batch", client.endpoint(), lfsRepoPrefix(repoType), repoID)
+	payload := map[string]any{
+		"operation": "upload",
+		"transfers": []string{"basic", "multipart"},
+		"objects":   []map[string]any{{"oid": oid, "size": size}},
+		"hash_algo": "sha256",
+		"ref":       map[string]string{"name": revision},
+	}
+	data, _ := json.Marshal(payload)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create LFS batch request: %w", err)
+	}
+	if err := client.authorize(req); err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Accept", lfsMediaType)
+	req.Header.Set("Content-Type", lfsMediaType)
+
+	resp, err := client.doRequestRetry(req, retryIdempotent)
+	if err != nil {
+		return nil, "", fmt.Errorf("LFS batch request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var batch lfsBatchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
+		return nil, "", fmt.Errorf("failed to decode LFS batch response: %w", err)
+	}
+	if len(batch.Objects) != 1 {
+		return nil, "", fmt.Errorf("LFS batch response has %d objects, expected 1", len(batch.Objects))
+	}
+	object := &batch.Objects[0]
+	if object.Error != nil {
+		return nil, "", fmt.Errorf("LFS object %s rejected: %d %s", oid, object.Error.Code, object.Error.Message)
+	}
+	return object, batch.Transfer, nil
+}
+
+// uploadLFS uploads src to LFS storage unless the Hub already has it, and returns its sha256.
+func (client *HuggingFaceClient) uploadLFS(ctx context.Context, repoType, repoID, revision string, src uploadSource) (string, error) {
+	oid, err := src.sha256()
+	if err != nil {
+		return "", fmt.Errorf("failed to hash file: %w", err)
+	}
+
+	object, transfer, err := client.lfsBatch(ctx, repoType, repoID, revision, oid, src.size)
+	if err != nil {
+		return "", err
+	}
+	if object.Actions.Upload == nil {
+		// The Hub already stores this object
+		return oid, nil
+	}
+
+	if transfer == "multipart" {
+		err = client.uploadMultipart(ctx, object.Actions.Upload, oid, src)
+	} else {
+		err = client.uploadSinglePart(ctx, object.Actions.Upload, src)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if verify := object.Actions.Verify; verify != nil {
+		data, _ := json.Marshal(map[string]any{"oid": oid, "size": src.size})
+		req, err := http.NewRequestWithContext(ctx, "POST", verify.Href, bytes.NewBuffer(data))
+		if err != nil {
+			return "", fmt.Errorf("failed to create LFS verify request: %w", err)
+		}
+		for k, v := range verify.Header {
+			req.Header.Set(k, v)
+		}
+		if err := client.authorize(req); err != nil {
+			return "", err
+		}
+		req.Header.Set("Accept", lfsMediaType)
+		req.Header.Set("Content-Type", lfsMediaType)
+
+		resp, err := client.doRequestRetry(req, retryIdempotent)
+		if err != nil {
+			return "", fmt.Errorf("LFS verification failed: %w", err)
+		}
+		resp.Body.Close()
+	}
+	return oid, nil
+}
+
+// newSectionRequest creates a request whose body can be replayed on retries.
+// The bytes read from body are reported to progress, if not nil.
+func newSectionRequest(ctx context.Context, method, url string, body *io.SectionReader, progress func(delta int64)) (*http.Request, error) {
+	var sent int64
+	open := func() io.Reader {
+		r := io.NewSectionReader(body, 0, body.Size())
+		if progress == nil {
+			return r
+		}
+		// A replayed body starts over
+		progress(-atomic.SwapInt64(&sent, 0))
+		return progressReader{r: r, sent: &sent, add: progress}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, open())
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = body.Size()
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(open()), nil
+	}
+	return req, nil
+}
+
+func (client *HuggingFaceClient) uploadSinglePart(ctx context.Context, upload *lfsAction, src uploadSource) error {
+	req, err := newSectionRequest(ctx, "PUT", upload.Href, src.section(0, src.size), src.progress)
+	if err != nil {
+		return fmt.Errorf("failed to create LFS upload request: %w", err)
+	}
+	for k, v := range upload.Header {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.doRequestRetry(req, retryIdempotent)
+	if err != nil {
+		return fmt.Errorf("LFS upload failed: %w", err)
+	}
+	return resp.Body.Close()
+}
+
+// uploadMultipart puts every part to its presigned URL, then tells the Hub
+// which ETags make up the object.
+func (client *HuggingFaceClient) uploadMultipart(ctx context.Context, upload *lfsAction, oid string, src uploadSource) error {
+	chunkSize, err := strconv.ParseInt(upload.Header["chunk_size"], 10, 64)
+	if err != nil || chunkSize <= 0 {
+		return fmt.Errorf("invalid LFS chunk size %q", upload.Header["chunk_size"])
+	}
+
+	var partNumbers []int
+	for k := range upload.Header {
+		if n, err := strconv.Atoi(k); err == nil {
+			partNumbers = append(partNumbers, n)
+		}
+	}
+	sort.Ints(partNumbers)
+	if want := int((src.size + chunkSize - 1) / chunkSize); len(partNumbers) != want {
+		return fmt.Errorf("LFS upload expects %d parts, got %d part URLs", want, len(partNumbers))
+	}
+
+	type part struct {
+		PartNumber int    `json:"partNumber"`
+		ETag       string `json:"etag"`
+	}
+	parts := make([]part, 0, len(partNumbers))
+	for _, n := range partNumbers {
+		offset := int64(n-1) * chunkSize
+		length := chunkSize
+		if offset+length > src.size {
+			length = src.size - offset
+		}
+
+		req, err := newSectionRequest(ctx, "PUT", upload.Header[strconv.Itoa(n)], src.section(offset, length), src.progress)
+		if err != nil {
+			return fmt.Errorf("failed to create request for part %d: %w", n, err)
+		}
+		resp, err := client.doRequestRetry(req, retryIdempotent)
+		if err != nil {
+			return fmt.Errorf("failed to upload part %d: %w", n, err)
+		}
+		resp.Body.Close()
+
+		etag := resp.Header.Get("ETag")
+		if etag == "" {
+			return fmt.Errorf("no ETag returned for part %d", n)
+		}
+		parts = append(parts, part{PartNumber: n, ETag: etag})
+	}
+
+	data, _ := json.Marshal(map[string]any{"oid": oid, "parts": parts})
+	req, err := http.NewRequestWithContext(ctx, "POST", upload.Href, bytes.NewBuffer(data))
+	if err != nil {
+		return fmt.Errorf("failed to create multipart completion request: %w", err)
+	}
+	req.Header.Set("Accept", lfsMediaType)
+	req.Header.Set("Content-Type", lfsMediaType)
+
+	resp, err := client.doRequestRetry(req, retryIdempotent)
+	if err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+	return resp.Body.Close()
+}
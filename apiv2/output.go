@@ -0,0 +1,285 @@
+package apiv2
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats of Request.Output. Only OutputTable uses colours; the others
+// emit the underlying structs with stable field names, for scripts.
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+	OutputCSV   = "csv"
+)
+
+// IsMachineOutput reports whether format is one of the machine-readable
+// formats, and errors on unknown ones. The empty format is OutputTable.
+func IsMachineOutput(format string) (bool, error) {
+	switch format {
+	case "", OutputTable:
+		return false, nil
+	case OutputJSON, OutputYAML, OutputCSV:
+		return true, nil
+	}
+	return false, fmt.Errorf("unknown output format %q, expected table, json, yaml or csv", format)
+}
+
+// csvRecords is implemented by the results that have a CSV form.
+type csvRecords interface {
+	csvHeader() []string
+	csvRows() [][]string
+}
+
+// writeOutput writes v to w in a machine-readable format.
+func writeOutput(w io.Writer, format string, v any) error {
+	switch format {
+	case OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+
+	case OutputYAML:
+		// Go through JSON so that YAML has the same field names
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		var generic any
+		if err := json.Unmarshal(data, &generic); err != nil {
+			return err
+		}
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(generic); err != nil {
+			return err
+		}
+		return enc.Close()
+
+	case OutputCSV:
+		records, ok := v.(csvRecords)
+		if !ok {
+			return fmt.Errorf("csv output is not available for %T", v)
+		}
+		cw := csv.NewWriter(w)
+		cw.Write(records.csvHeader())
+		cw.WriteAll(records.csvRows())
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+// csvValue formats a decoded JSON value for a CSV cell.
+func csvValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func (meta *MetadataResponse) csvHeader() []string {
+	return []string{"name", "@type", "creator", "url", "license", "keywords", "description"}
+}
+
+func (meta *MetadataResponse) csvRows() [][]string {
+	return [][]string{{
+		meta.Name, meta.Type, meta.Creator["name"], meta.URL, meta.License,
+		strings.Join(meta.Keywords, ";"), meta.Description,
+	}}
+}
+
+// Statistics become one row per column of the dataset, with the keys of
+// every statistic as CSV columns.
+func (stat *Statistics) csvHeader() []string {
+	keys := make(map[string]bool)
+	for _, s := range stat.Statistics {
+		for k := range s {
+			keys[k] = true
+		}
+	}
+	header := make([]string, 0, len(keys))
+	for k := range keys {
+		header = append(header, k)
+	}
+	sort.Strings(header)
+	return header
+}
+
+func (stat *Statistics) csvRows() [][]string {
+	header := stat.csvHeader()
+	rows := make([][]string, 0, len(stat.Statistics))
+	for _, s := range stat.Statistics {
+		row := make([]string, len(header))
+		for i, k := range header {
+			row[i] = csvValue(s[k])
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// fileListing is the output of repo-files -action list.
+type fileListing []HFFile
+
+func (files fileListing) csvHeader() []string {
+	return []string{"path", "type", "size", "oid", "lfsOid", "lastCommitId", "lastCommitDate", "lastCommitTitle"}
+}
+
+func (files fileListing) csvRows() [][]string {
+	rows := make([][]string, 0, len(files))
+	for _, f := range files {
+		row := []string{f.Path, f.Type, strconv.FormatUint(uint64(f.Size), 10), f.Oid, "", "", "", ""}
+		if f.LFS != nil {
+			row[4] = f.LFS.Oid
+		}
+		if c := f.LastCommit; c != nil {
+			row[5], row[6], row[7] = c.ID, c.Date.Format(time.RFC3339), c.Title
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func (r *VerifyReport) csvHeader() []string {
+	return []string{"path", "status"}
+}
+
+func (r *VerifyReport) csvRows() [][]string {
+	var rows [][]string
+	for _, group := range []struct {
+		status string
+		paths  []string
+	}{{"verified", r.Verified}, {"missing", r.Missing}, {"extra", r.Extra}, {"corrupted", r.Corrupted}} {
+		for _, path := range group.paths {
+			rows = append(rows, []string{path, group.status})
+		}
+	}
+	return rows
+}
+
+// TransferResult is the outcome of the transfer of one file.
+type TransferResult struct {
+	Path string `json:"path"`
+	// Status is "downloaded", "uploaded", "deleted", "ignored" or "failed".
+	Status    string `json:"status"`
+	LocalPath string `json:"localPath,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// TransferResults are the outcomes of the files of a download or an upload.
+// Commit is only set for uploads.
+type TransferResults struct {
+	Commit *CommitInfo       `json:"commit,omitempty"`
+	Files  []*TransferResult `json:"files"`
+}
+
+func (r *TransferResults) csvHeader() []string {
+	return []string{"path", "status", "localPath", "error", "commitOid", "pullRequestRevision"}
+}
+
+func (r *TransferResults) csvRows() [][]string {
+	var commit, pullRequest string
+	if r.Commit != nil {
+		commit, pullRequest = r.Commit.CommitOid, r.Commit.PullRequestRevision
+	}
+	rows := make([][]string, 0, len(r.Files))
+	for _, f := range r.Files {
+		rows = append(rows, []string{f.Path, f.Status, f.LocalPath, f.Error, commit, pullRequest})
+	}
+	return rows
+}
+
+// transferReport gathers the TransferResults of a batch, and shows progress
+// bars while it runs in table output.
+type transferReport struct {
+	action string
+	bars   *transferProgress
+	mu     sync.Mutex
+	byPath map[string]*TransferResult
+	TransferResults
+}
+
+// newTransferReport starts the report of action ("download", "snapshot",
+// "upload" or "delete") on files.
+func newTransferReport(machineOutput bool, action string, files int) *transferReport {
+	t := &transferReport{action: action, byPath: make(map[string]*TransferResult)}
+	if !machineOutput {
+		t.bars = newTransferProgress(action, files)
+	}
+	return t
+}
+
+// update is the ProgressFunc of the client during the transfer.
+func (t *transferReport) update(pathInRepo string, transferred, total int64) {
+	if t.bars != nil {
+		t.bars.update(pathInRepo, transferred, total)
+	}
+}
+
+func (t *transferReport) set(pathInRepo, status, localPath string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	res, ok := t.byPath[pathInRepo]
+	if !ok {
+		res = &TransferResult{Path: pathInRepo}
+		t.byPath[pathInRepo] = res
+		t.Files = append(t.Files, res)
+	}
+	res.Status, res.LocalPath = status, localPath
+	if err != nil {
+		res.Error = err.Error()
+	}
+}
+
+// finish records the outcome of pathInRepo, saved at localPath if any.
+func (t *transferReport) finish(pathInRepo, localPath string, err error) {
+	status := "downloaded"
+	switch t.action {
+	case "upload":
+		status = "uploaded"
+	case "delete":
+		status = "deleted"
+	}
+	if err != nil {
+		status, localPath = "failed", ""
+	}
+	t.set(pathInRepo, status, localPath, err)
+	if t.bars != nil {
+		t.bars.finish(pathInRepo, err)
+	}
+}
+
+// ignore records that the Hub skipped pathInRepo.
+func (t *transferReport) ignore(pathInRepo string) {
+	t.set(pathInRepo, "ignored", "", nil)
+}
+
+// stop removes the progress bars and sorts the results by path.
+func (t *transferReport) stop() *TransferResults {
+	if t.bars != nil {
+		t.bars.stop()
+	}
+	sort.Slice(t.Files, func(i, j int) bool { return t.Files[i].Path < t.Files[j].Path })
+	if t.Files == nil {
+		t.Files = []*TransferResult{}
+	}
+	return &t.TransferResults
+}
@@ -0,0 +1,75 @@
+package apiv2
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MatchPattern reports whether name matches the shell pattern, fnmatch style
+// as in huggingface_hub: '*' also matches '/', so "*.bin" matches "a/b.bin".
+// A pattern ending with '/' matches everything in that folder.
+func MatchPattern(pattern, name string) bool {
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "*"
+	}
+	re, err := regexp.Compile(translatePattern(pattern))
+	if err != nil {
+		return pattern == name
+	}
+	return re.MatchString(name)
+}
+
+// translatePattern turns a shell pattern into an anchored regular expression.
+func translatePattern(pattern string) string {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return sb.String()
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if MatchPattern(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterPaths keeps the paths that match at least one include pattern (all of
+// them if include is empty) and no exclude pattern.
+func FilterPaths(paths, include, exclude []string) []string {
+	var res []string
+	for _, p := range paths {
+		if len(include) > 0 && !matchAny(include, p) {
+			continue
+		}
+		if matchAny(exclude, p) {
+			continue
+		}
+		res = append(res, p)
+	}
+	return res
+}
@@ -0,0 +1,51 @@
+package apiv2
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.bin", "model.bin", true},
+		{"*.bin", "sub/model.bin", true},
+		{"*.bin", "model.safetensors", false},
+		{"model-?.json", "model-1.json", true},
+		{"model-?.json", "model-10.json", false},
+		{"model-[0-9].json", "model-7.json", true},
+		{"model-[!0-9].json", "model-7.json", false},
+		{"model-[!0-9].json", "model-a.json", true},
+		{"onnx/", "onnx/model.onnx", true},
+		{"onnx/", "onnx.json", false},
+		{"config.json", "config.json", true},
+		{"config.json", "configXjson", false},
+		{"[unclosed", "[unclosed", true},
+	}
+	for _, tt := range tests {
+		if got := MatchPattern(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("MatchPattern(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFilterPaths(t *testing.T) {
+	paths := []string{"config.json", "model.safetensors", "model.bin", "onnx/model.onnx", "README.md"}
+	tests := []struct {
+		include, exclude []string
+		want             []string
+	}{
+		{nil, nil, paths},
+		{[]string{"*.json", "*.safetensors"}, nil, []string{"config.json", "model.safetensors"}},
+		{nil, []string{"*.bin", "onnx/"}, []string{"config.json", "model.safetensors", "README.md"}},
+		{[]string{"model.*"}, []string{"*.bin"}, []string{"model.safetensors"}},
+		{[]string{"*.h5"}, nil, nil},
+	}
+	for _, tt := range tests {
+		if got := FilterPaths(paths, tt.include, tt.exclude); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FilterPaths(%q, %q) = %q, want %q", tt.include, tt.exclude, got, tt.want)
+		}
+	}
+}
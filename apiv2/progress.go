@@ -0,0 +1,110 @@
+package apiv2
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/progress"
+	"github.com/k0kubun/go-ansi"
+)
+
+const progressUpdateFrequency = 100 * time.Millisecond
+
+// transferProgress renders a bar per file in flight, with its bytes,
+// throughput and ETA, under an aggregate bar counting the finished files.
+type transferProgress struct {
+	pw       progress.Writer
+	action   string
+	files    int
+	overall  *progress.Tracker
+	mu       sync.Mutex
+	trackers map[string]*progress.Tracker
+}
+
+func newTransferProgress(action string, files int) *transferProgress {
+	pw := progress.NewWriter()
+	pw.SetOutputWriter(ansi.NewAnsiStdout())
+	pw.SetAutoStop(false)
+	pw.SetMessageLength(40)
+	pw.SetTrackerLength(20)
+	pw.SetTrackerPosition(progress.PositionRight)
+	pw.SetUpdateFrequency(progressUpdateFrequency)
+	pw.SetStyle(progress.StyleBlocks)
+	pw.Style().Visibility.ETA = true
+	pw.Style().Visibility.Speed = true
+	pw.Style().Visibility.TrackerOverall = false
+
+	p := &transferProgress{
+		pw:       pw,
+		action:   action,
+		files:    files,
+		overall:  &progress.Tracker{Total: int64(files)},
+		trackers: make(map[string]*progress.Tracker),
+	}
+	p.overall.Message = p.overallMessage(0)
+	pw.AppendTracker(p.overall)
+	go pw.Render()
+	return p
+}
+
+func (p *transferProgress) overallMessage(done int64) string {
+	color := getGradientColor(float64(done) / float64(p.files))
+	return fmt.Sprintf("%sProcessing %s...\x1b[0m", color, p.action)
+}
+
+func (p *transferProgress) tracker(pathInRepo string) *progress.Tracker {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	t, ok := p.trackers[pathInRepo]
+	if !ok {
+		t = &progress.Tracker{Message: pathInRepo, Units: progress.UnitsBytes, RemoveOnCompletion: true}
+		p.trackers[pathInRepo] = t
+		p.pw.AppendTracker(t)
+	}
+	return t
+}
+
+// update is the ProgressFunc of the client while the bars are shown.
+func (p *transferProgress) update(pathInRepo string, transferred, total int64) {
+	t := p.tracker(pathInRepo)
+	if total > 0 {
+		t.UpdateTotal(total)
+	}
+	t.SetValue(transferred)
+}
+
+// finish marks the transfer of pathInRepo as done, or failed if err is not nil.
+func (p *transferProgress) finish(pathInRepo string, err error) {
+	t := p.tracker(pathInRepo)
+	if err != nil {
+		t.MarkAsErrored()
+	} else {
+		t.MarkAsDone()
+	}
+	p.overall.Increment(1)
+	p.overall.UpdateMessage(p.overallMessage(p.overall.Value()))
+}
+
+// stop renders the bars one last time and waits for the renderer to exit.
+func (p *transferProgress) stop() {
+	p.overall.MarkAsDone()
+	time.Sleep(progressUpdateFrequency)
+	p.pw.Stop()
+	for p.pw.IsRenderInProgress() {
+		time.Sleep(progressUpdateFrequency / 10)
+	}
+}
+
+// printTransferFailures lists every file of a batch that could not be transferred.
+func printTransferFailures(err error) {
+	var transferErr *TransferError
+	if !errors.As(err, &transferErr) {
+		return
+	}
+	fmt.Printf("\n%d of %d files failed:\n", len(transferErr.Failures), transferErr.Total)
+	for _, f := range transferErr.Failures {
+		fmt.Printf("  ❌ %s: %v\n", f.Path, f.Err)
+	}
+}
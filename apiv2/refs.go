@@ -0,0 +1,144 @@
+package apiv2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// GitRef is a branch or a tag and the commit it points to.
+type GitRef struct {
+	Name         string `json:"name"`
+	Ref          string `json:"ref"`
+	TargetCommit string `json:"targetCommit"`
+}
+
+// GitRefs are the branches and tags of a repository.
+type GitRefs struct {
+	Branches []GitRef `json:"branches"`
+	Tags     []GitRef `json:"tags"`
+}
+
+func (refs *GitRefs) csvHeader() []string {
+	return []string{"type", "name", "ref", "targetCommit"}
+}
+
+func (refs *GitRefs) csvRows() [][]string {
+	var rows [][]string
+	for _, ref := range refs.Branches {
+		rows = append(rows, []string{"branch", ref.Name, ref.Ref, ref.TargetCommit})
+	}
+	for _, ref := range refs.Tags {
+		rows = append(rows, []string{"tag", ref.Name, ref.Ref, ref.TargetCommit})
+	}
+	return rows
+}
+
+func (client *HuggingFaceClient) ListRefs(repoType, repoID string) (*GitRefs, error) {
+	return client.ListRefsContext(context.Background(), repoType, repoID)
+}
+
+func (client *HuggingFaceClient) ListRefsContext(ctx context.Context, repoType, repoID string) (*GitRefs, error) {
+	url := fmt.Sprintf("%s/api/%s/%s/refs", client.endpoint(), repoType+"s", repoID)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refs request: %w", err)
+	}
+	if err := client.authorize(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := client.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	refs := &GitRefs{}
+	if err := json.NewDecoder(resp.Body).Decode(refs); err != nil {
+		return nil, fmt.Errorf("failed to decode refs: %w", err)
+	}
+	if refs.Branches == nil {
+		refs.Branches = []GitRef{}
+	}
+	if refs.Tags == nil {
+		refs.Tags = []GitRef{}
+	}
+	return refs, nil
+}
+
+func (client *HuggingFaceClient) CreateBranch(repoType, repoID, branch, startingPoint string) error {
+	return client.CreateBranchContext(context.Background(), repoType, repoID, branch, startingPoint)
+}
+
+// CreateBranchContext creates branch at the startingPoint revision; an empty
+// startingPoint means the head of DefaultRevision.
+func (client *HuggingFaceClient) CreateBranchContext(ctx context.Context, repoType, repoID, branch, startingPoint string) error {
+	payload := map[string]string{}
+	if startingPoint != "" {
+		payload["startingPoint"] = startingPoint
+	}
+	url := fmt.Sprintf("%s/api/%s/%s/branch/%s", client.endpoint(), repoType+"s", repoID, escapeRevision(branch))
+	return client.sendRefRequest(ctx, "POST", url, payload)
+}
+
+func (client *HuggingFaceClient) DeleteBranch(repoType, repoID, branch string) error {
+	return client.DeleteBranchContext(context.Background(), repoType, repoID, branch)
+}
+
+func (client *HuggingFaceClient) DeleteBranchContext(ctx context.Context, repoType, repoID, branch string) error {
+	url := fmt.Sprintf("%s/api/%s/%s/branch/%s", client.endpoint(), repoType+"s", repoID, escapeRevision(branch))
+	return client.sendRefRequest(ctx, "DELETE", url, nil)
+}
+
+func (client *HuggingFaceClient) CreateTag(repoType, repoID, tag, revision, message string) error {
+	return client.CreateTagContext(context.Background(), repoType, repoID, tag, revision, message)
+}
+
+// CreateTagContext tags revision, DefaultRevision if empty. A message makes
+// it an annotated tag.
+func (client *HuggingFaceClient) CreateTagContext(ctx context.Context, repoType, repoID, tag, revision, message string) error {
+	payload := map[string]string{"tag": tag}
+	if message != "" {
+		payload["message"] = message
+	}
+	url := fmt.Sprintf("%s/api/%s/%s/tag/%s", client.endpoint(), repoType+"s", repoID, escapeRevision(revision))
+	return client.sendRefRequest(ctx, "POST", url, payload)
+}
+
+func (client *HuggingFaceClient) DeleteTag(repoType, repoID, tag string) error {
+	return client.DeleteTagContext(context.Background(), repoType, repoID, tag)
+}
+
+func (client *HuggingFaceClient) DeleteTagContext(ctx context.Context, repoType, repoID, tag string) error {
+	url := fmt.Sprintf("%s/api/%s/%s/tag/%s", client.endpoint(), repoType+"s", repoID, escapeRevision(tag))
+	return client.sendRefRequest(ctx, "DELETE", url, nil)
+}
+
+// sendRefRequest creates or deletes a ref. A nil payload sends no body.
+func (client *HuggingFaceClient) sendRefRequest(ctx context.Context, method, url string, payload map[string]string) error {
+	var body io.Reader
+	if payload != nil {
+		data, _ := json.Marshal(payload)
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return fmt.Errorf("failed to create ref request: %w", err)
+	}
+	if err := client.authorize(req); err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.doRequestRetry(req, retryRejected)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
@@ -0,0 +1,195 @@
+package apiv2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of tries, the first one included. 1 disables retries.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry; it doubles on every attempt.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the computed backoff. A Retry-After header sent by the Hub is honoured as is.
+	MaxBackoff time.Duration
+	// OnRetry, when set, is called before sleeping for every retry.
+	OnRetry func(RetryEvent)
+}
+
+// RetryEvent describes a retry about to happen.
+type RetryEvent struct {
+	Method  string
+	URL     string
+	Attempt int // the attempt that just failed, starting at 1
+	Wait    time.Duration
+	Err     error
+}
+
+// DefaultRetryPolicy is used by clients that have no RetryPolicy set.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: time.Second,
+	MaxBackoff:     30 * time.Second,
+}
+
+// NoRetry makes every request fail on the first error.
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+// WithRetryPolicy replaces DefaultRetryPolicy for this client.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(client *HuggingFaceClient) {
+		client.RetryPolicy = &policy
+	}
+}
+
+// retryMode tells which failures of a request may be retried.
+type retryMode int
+
+const (
+	// retryNever sends the request once.
+	retryNever retryMode = iota
+	// retryIdempotent retries network errors, 429 and 5xx: the request can be sent any number of times.
+	retryIdempotent
+	// retryRejected retries only when the Hub says it did not process the request (429, 503),
+	// used for commits and other writes that are safe to replay but must not be applied twice.
+	retryRejected
+)
+
+func defaultRetryMode(method string) retryMode {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return retryIdempotent
+	}
+	return retryNever
+}
+
+func (client *HuggingFaceClient) retryPolicy() RetryPolicy {
+	if client.RetryPolicy == nil {
+		return DefaultRetryPolicy
+	}
+	return *client.RetryPolicy
+}
+
+// shouldRetry reports whether err is worth another attempt under mode.
+func shouldRetry(mode retryMode, err error) bool {
+	var hubErr *HubError
+	if errors.As(err, &hubErr) {
+		switch hubErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+			return mode != retryNever
+		case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+			return mode == retryIdempotent
+		}
+		return false
+	}
+	// Transport errors: the request may or may not have reached the Hub.
+	return mode == retryIdempotent
+}
+
+// backoff returns the wait before the retry that follows attempt.
+func (policy RetryPolicy) backoff(attempt int, err error) time.Duration {
+	var hubErr *HubError
+	if errors.As(err, &hubErr) && hubErr.RetryAfter > 0 {
+		return hubErr.RetryAfter
+	}
+
+	wait := policy.InitialBackoff
+	for i := 1; i < attempt && (policy.MaxBackoff <= 0 || wait < policy.MaxBackoff); i++ {
+		wait *= 2
+	}
+	if policy.MaxBackoff > 0 && wait > policy.MaxBackoff {
+		wait = policy.MaxBackoff
+	}
+	if wait <= 0 {
+		return 0
+	}
+	// Jitter between half and the full backoff keeps parallel jobs from retrying in lockstep
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// parseRetryAfter understands both forms of the Retry-After header: seconds and HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// doRequestRetry sends req, retrying it according to mode and the client's policy.
+// The request body is replayed with req.GetBody, which http.NewRequest sets for in-memory bodies.
+func (client *HuggingFaceClient) doRequestRetry(req *http.Request, mode retryMode) (*http.Response, error) {
+	policy := client.retryPolicy()
+	if req.Body != nil && req.GetBody == nil {
+		mode = retryNever
+	}
+
+	refreshed := false
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("failed to replay request body: %w", err)
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		resp, err := client.sendRequest(attemptReq)
+		if err == nil {
+			return resp, nil
+		}
+		var hubErr *HubError
+		if !refreshed && errors.As(err, &hubErr) && hubErr.StatusCode == http.StatusUnauthorized &&
+			req.Header.Get("Authorization") != "" && (req.Body == nil || req.GetBody != nil) && client.invalidateToken() {
+			// The token may have expired: try once more with a fresh one
+			refreshed = true
+			if err := client.authorize(req); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if attempt >= policy.MaxAttempts || req.Context().Err() != nil || !shouldRetry(mode, err) {
+			return nil, err
+		}
+
+		wait := policy.backoff(attempt, err)
+		if policy.OnRetry != nil {
+			policy.OnRetry(RetryEvent{
+				Method:  req.Method,
+				URL:     req.URL.String(),
+				Attempt: attempt,
+				Wait:    wait,
+				Err:     err,
+			})
+		}
+		if err := sleepContext(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func sleepContext(ctx context.Context, wait time.Duration) error {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
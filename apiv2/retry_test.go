@@ -0,0 +1,129 @@
+package apiv2
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestShouldRetry(t *testing.T) {
+	network := errors.New("connection reset")
+	tests := []struct {
+		mode retryMode
+		err  error
+		want bool
+	}{
+		{retryIdempotent, &HubError{StatusCode: 429}, true},
+		{retryIdempotent, &HubError{StatusCode: 500}, true},
+		{retryIdempotent, &HubError{StatusCode: 502}, true},
+		{retryIdempotent, &HubError{StatusCode: 503}, true},
+		{retryIdempotent, &HubError{StatusCode: 504}, true},
+		{retryIdempotent, &HubError{StatusCode: 404}, false},
+		{retryIdempotent, network, true},
+		{retryRejected, &HubError{StatusCode: 429}, true},
+		{retryRejected, &HubError{StatusCode: 503}, true},
+		{retryRejected, &HubError{StatusCode: 500}, false},
+		{retryRejected, network, false},
+		{retryNever, &HubError{StatusCode: 503}, false},
+		{retryNever, network, false},
+	}
+	for _, tt := range tests {
+		if got := shouldRetry(tt.mode, tt.err); got != tt.want {
+			t.Errorf("shouldRetry(%d, %v) = %v, want %v", tt.mode, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	tests := []struct {
+		attempt  int
+		err      error
+		min, max time.Duration
+	}{
+		{1, nil, 500 * time.Millisecond, time.Second},
+		{2, nil, time.Second, 2 * time.Second},
+		{3, nil, 2 * time.Second, 4 * time.Second},
+		{4, nil, 2500 * time.Millisecond, 5 * time.Second},
+		{50, nil, 2500 * time.Millisecond, 5 * time.Second},
+		// Retry-After is honoured as is, even above MaxBackoff
+		{1, &HubError{StatusCode: 429, RetryAfter: time.Minute}, time.Minute, time.Minute},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if got := policy.backoff(tt.attempt, tt.err); got < tt.min || got > tt.max {
+				t.Errorf("backoff(%d, %v) = %v, want between %v and %v", tt.attempt, tt.err, got, tt.min, tt.max)
+				break
+			}
+		}
+	}
+	if got := (RetryPolicy{}).backoff(3, nil); got != 0 {
+		t.Errorf("backoff without InitialBackoff = %v, want 0", got)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value    string
+		min, max time.Duration
+	}{
+		{"", 0, 0},
+		{"7", 7 * time.Second, 7 * time.Second},
+		{"0", 0, 0},
+		{"-3", 0, 0},
+		{"soon", 0, 0},
+		{time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), 50 * time.Second, time.Minute},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value); got < tt.min || got > tt.max {
+			t.Errorf("parseRetryAfter(%q) = %v, want between %v and %v", tt.value, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestDoRequestRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		statuses []int
+		wantErr  bool
+		wantSent int
+	}{
+		{"GET retried until it succeeds", "GET", []int{503, 500, 200}, false, 3},
+		{"GET gives up after MaxAttempts", "GET", []int{502, 502, 502, 502}, true, 3},
+		{"GET not retried on 404", "GET", []int{404, 200}, true, 1},
+		{"POST not retried", "POST", []int{503, 200}, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statuses[sent])
+				sent++
+			}))
+			defer server.Close()
+
+			var events []RetryEvent
+			client := NewHuggingFaceClient("", WithRetryPolicy(RetryPolicy{
+				MaxAttempts:    3,
+				InitialBackoff: time.Millisecond,
+				OnRetry:        func(e RetryEvent) { events = append(events, e) },
+			}))
+			req, _ := http.NewRequestWithContext(context.Background(), tt.method, server.URL, nil)
+			resp, err := client.doRequest(req)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != tt.wantErr || sent != tt.wantSent {
+				t.Errorf("doRequest = %v after %d requests, want error %v after %d", err, sent, tt.wantErr, tt.wantSent)
+			}
+			if len(events) != sent-1 {
+				t.Errorf("OnRetry called %d times, want %d", len(events), sent-1)
+			}
+		})
+	}
+}
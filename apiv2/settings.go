@@ -0,0 +1,108 @@
+package apiv2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Gated access modes of RepoSettings.Gated.
+const (
+	// GatedAuto grants access to every user who accepts the conditions.
+	GatedAuto = "auto"
+	// GatedManual lets the owners review every access request.
+	GatedManual = "manual"
+	// GatedOff opens the repository to everyone who can see it.
+	GatedOff = "false"
+)
+
+// RepoSettings are the changes made by UpdateRepoSettings. Nil or empty
+// fields are left as they are.
+type RepoSettings struct {
+	Private *bool
+	// Gated is GatedAuto, GatedManual or GatedOff.
+	Gated string
+}
+
+// MarshalJSON writes the body the Hub expects, where gated is "auto",
+// "manual" or the boolean false.
+func (s RepoSettings) MarshalJSON() ([]byte, error) {
+	body := make(map[string]any)
+	if s.Private != nil {
+		body["private"] = *s.Private
+	}
+	switch s.Gated {
+	case "":
+	case GatedOff:
+		body["gated"] = false
+	case GatedAuto, GatedManual:
+		body["gated"] = s.Gated
+	default:
+		return nil, fmt.Errorf("invalid gated mode %q, expected auto, manual or false", s.Gated)
+	}
+	return json.Marshal(body)
+}
+
+func (client *HuggingFaceClient) UpdateRepoSettings(repoType, repoID string, settings RepoSettings) error {
+	return client.UpdateRepoSettingsContext(context.Background(), repoType, repoID, settings)
+}
+
+// UpdateRepoSettingsContext changes the visibility or the gated access of a repository.
+func (client *HuggingFaceClient) UpdateRepoSettingsContext(ctx context.Context, repoType, repoID string, settings RepoSettings) error {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	if string(data) == "{}" {
+		return fmt.Errorf("no repository setting to change")
+	}
+
+	url := fmt.Sprintf("%s/api/%s/%s/settings", client.endpoint(), repoType+"s", repoID)
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewBuffer(data))
+	if err != nil {
+		return fmt.Errorf("failed to create settings request: %w", err)
+	}
+	if err := client.authorize(req); err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.doRequestRetry(req, retryRejected)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (client *HuggingFaceClient) MoveRepo(repoType, fromRepo, toRepo string) error {
+	return client.MoveRepoContext(context.Background(), repoType, fromRepo, toRepo)
+}
+
+// MoveRepoContext renames a repository, or moves it to another user or
+// organisation. The Hub redirects the old name to the new one.
+func (client *HuggingFaceClient) MoveRepoContext(ctx context.Context, repoType, fromRepo, toRepo string) error {
+	if len(strings.Split(toRepo, "/")) != 2 {
+		return fmt.Errorf("repo name must be in format 'username/repo-name'")
+	}
+
+	url := fmt.Sprintf("%s/api/repos/move", client.endpoint())
+	payload := map[string]string{"fromRepo": fromRepo, "toRepo": toRepo, "type": repoType}
+	data, _ := json.Marshal(payload)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(data))
+	if err != nil {
+		return fmt.Errorf("failed to create move request: %w", err)
+	}
+	if err := client.authorize(req); err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.doRequestRetry(req, retryRejected)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
@@ -0,0 +1,130 @@
+package apiv2
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// SnapshotOptions selects what SnapshotDownload fetches and where it goes.
+type SnapshotOptions struct {
+	// Include and Exclude are glob patterns, see MatchPattern.
+	Include []string
+	Exclude []string
+	// LocalDir receives the files, hard-linked to the cache where possible,
+	// keeping the repository's directory structure. Empty leaves them in the
+	// cache only.
+	LocalDir string
+	// Progress, when set, is called after each file is downloaded, with the
+	// number of files done so far out of total.
+	Progress func(pathInRepo string, done, total int)
+}
+
+// SnapshotDownload downloads every file of the repository at revision that
+// passes the patterns of opts, through the local cache. It returns LocalDir,
+// or the snapshot folder in the cache if LocalDir is empty.
+func (client *HuggingFaceClient) SnapshotDownload(repoType, repoID, revision string, opts SnapshotOptions) (string, error) {
+	return client.SnapshotDownloadContext(context.Background(), repoType, repoID, revision, opts)
+}
+
+func (client *HuggingFaceClient) SnapshotDownloadContext(ctx context.Context, repoType, repoID, revision string, opts SnapshotOptions) (string, error) {
+	files, err := client.SnapshotFilesContext(ctx, repoType, repoID, revision, opts.Include, opts.Exclude)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("no file of %s matches the given patterns", repoID)
+	}
+
+	var mu sync.Mutex
+	done := 0
+	snapshotDir, err := client.downloadFiles(ctx, repoType, repoID, revision, files, opts.LocalDir, false, func(file, _ string, err error) {
+		if err != nil || opts.Progress == nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		opts.Progress(file, done, len(files))
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if opts.LocalDir != "" {
+		return opts.LocalDir, nil
+	}
+	return snapshotDir, nil
+}
+
+// downloadFiles downloads files to localDir, keeping their paths, with up to
+// the client's Concurrency transfers at once. Files go through the cache
+// unless noCache is set, which needs a localDir. finished is called once per
+// file with where it was saved, from the goroutine that transferred it. It
+// returns the snapshot folder in the cache. A path that is absolute or goes
+// up with ".." is rejected before anything is downloaded, as it would be
+// saved out of localDir.
+func (client *HuggingFaceClient) downloadFiles(ctx context.Context, repoType, repoID, revision string, files []string, localDir string, noCache bool, finished func(file, localPath string, err error)) (string, error) {
+	for _, file := range files {
+		if err := checkRepoPath(file); err != nil {
+			return "", err
+		}
+	}
+
+	var mu sync.Mutex
+	var snapshotDir string
+	err := forEachParallel(ctx, client.concurrency(), files, func(_ int, file string) error {
+		localPath, err := func() (string, error) {
+			var dest string
+			if localDir != "" {
+				dest = filepath.Join(localDir, filepath.FromSlash(file))
+				if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+					return "", err
+				}
+			}
+			if noCache {
+				return dest, client.DownloadToPathContext(ctx, repoType, repoID, revision, file, dest)
+			}
+
+			cached, err := client.DownloadToCacheContext(ctx, repoType, repoID, revision, file)
+			if err != nil {
+				return "", err
+			}
+			mu.Lock()
+			snapshotDir = filepath.Clean(strings.TrimSuffix(cached, filepath.FromSlash(file)))
+			mu.Unlock()
+			if dest == "" {
+				return cached, nil
+			}
+			if err := linkFile(cached, dest); err != nil {
+				return "", fmt.Errorf("failed to save %s: %w", dest, err)
+			}
+			return dest, nil
+		}()
+		if finished != nil {
+			finished(file, localPath, err)
+		}
+		return err
+	})
+	return snapshotDir, err
+}
+
+// SnapshotFiles lists the files of the repository at revision that pass the include and exclude patterns.
+func (client *HuggingFaceClient) SnapshotFiles(repoType, repoID, revision string, include, exclude []string) ([]string, error) {
+	return client.SnapshotFilesContext(context.Background(), repoType, repoID, revision, include, exclude)
+}
+
+func (client *HuggingFaceClient) SnapshotFilesContext(ctx context.Context, repoType, repoID, revision string, include, exclude []string) ([]string, error) {
+	entries, err := client.listTree(ctx, repoType, repoID, revision, "", true, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	var paths []string
+	for _, file := range onlyFiles(entries) {
+		paths = append(paths, file.Path)
+	}
+	return FilterPaths(paths, include, exclude), nil
+}
@@ -1,6 +1,7 @@
 package apiv2
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -16,14 +17,22 @@ type Statistics struct {
 
 // GetDatasetStatistics fetches the statistics for a specified dataset and split.
 func (client *HuggingFaceClient) GetDatasetStatistics(repoName, split string) (*Statistics, error) {
+	return client.GetDatasetStatisticsContext(context.Background(), repoName, split)
+}
+
+// GetDatasetStatisticsContext is GetDatasetStatistics with a caller-supplied context.
+func (client *HuggingFaceClient) GetDatasetStatisticsContext(ctx context.Context, repoName, split string) (*Statistics, error) {
 	// Construct the API URL for the specified dataset and split.
-	url := fmt.Sprintf("https://datasets-server.huggingface.co/statistics?dataset=%s&config=cola&split=%s", repoName, split)
+	url := fmt.Sprintf("%s/statistics?dataset=%s&config=cola&split=%s", client.datasetsServerEndpoint(), repoName, split)
 
 	// Create a new HTTP GET request.
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	if err := client.authorizeDatasetsServer(req); err != nil {
+		return nil, err
+	}
 
 	// Execute the request using the client's configured doRequest method.
 	res, err := client.doRequest(req)
@@ -46,3 +55,13 @@ func (client *HuggingFaceClient) GetDatasetStatistics(repoName, split string) (*
 
 	return &stat, nil
 }
+
+// authorizeDatasetsServer authorizes a request to the datasets-server only if
+// it belongs to the Hub the token is for: the default datasets-server of the
+// default endpoint, or one on the host of the endpoint.
+func (client *HuggingFaceClient) authorizeDatasetsServer(req *http.Request) error {
+	if client.endpoint() == DefaultEndpoint && client.datasetsServerEndpoint() == DefaultDatasetsServerEndpoint {
+		return client.authorize(req)
+	}
+	return client.authorizePage(req)
+}
@@ -0,0 +1,132 @@
+package apiv2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Directions of a sync between a local folder and a repository.
+const (
+	// SyncPush makes the repository match the local folder.
+	SyncPush = "push"
+	// SyncPull makes the local folder match the repository.
+	SyncPull = "pull"
+)
+
+// SyncPlan is what a sync changes at its destination, the repository for
+// SyncPush or the local folder for SyncPull.
+type SyncPlan struct {
+	Direction string `json:"direction"`
+	// New files are only at the source, Changed ones differ in size or
+	// checksum; both are copied to the destination.
+	New     []string `json:"new"`
+	Changed []string `json:"changed"`
+	// Deleted files are only at the destination, and removed from it.
+	Deleted []string `json:"deleted"`
+	// Unchanged counts the files that are the same on both sides.
+	Unchanged int `json:"unchanged"`
+}
+
+// Transfers returns the New and Changed files, sorted.
+func (p *SyncPlan) Transfers() []string {
+	files := append(append([]string{}, p.New...), p.Changed...)
+	sort.Strings(files)
+	return files
+}
+
+// Empty reports whether the destination already matches the source.
+func (p *SyncPlan) Empty() bool {
+	return len(p.New) == 0 && len(p.Changed) == 0 && len(p.Deleted) == 0
+}
+
+func (p *SyncPlan) csvHeader() []string {
+	return []string{"path", "change"}
+}
+
+func (p *SyncPlan) csvRows() [][]string {
+	var rows [][]string
+	for _, file := range p.New {
+		rows = append(rows, []string{file, "new"})
+	}
+	for _, file := range p.Changed {
+		rows = append(rows, []string{file, "changed"})
+	}
+	for _, file := range p.Deleted {
+		rows = append(rows, []string{file, "deleted"})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i][0] < rows[j][0] })
+	return rows
+}
+
+func (client *HuggingFaceClient) PlanSync(direction, repoType, repoID, revision, localDir string, delete bool) (*SyncPlan, error) {
+	return client.PlanSyncContext(context.Background(), direction, repoType, repoID, revision, localDir, delete)
+}
+
+// PlanSyncContext compares localDir with revision of a repository the way
+// VerifyLocalDir does, by size and checksum, and returns what a sync in
+// direction has to change. Files only at the destination are left alone
+// unless delete is set. A missing localDir is empty when pulling.
+//
+// The paths the .huggerignore files of localDir ignore are out of the sync
+// on both sides, as are the .git folder and the leftovers of interrupted
+// downloads. .gitattributes, which tells the Hub what is stored in LFS, is
+// never deleted from the repository.
+func (client *HuggingFaceClient) PlanSyncContext(ctx context.Context, direction, repoType, repoID, revision, localDir string, delete bool) (*SyncPlan, error) {
+	if direction != SyncPush && direction != SyncPull {
+		return nil, fmt.Errorf("invalid sync direction %q, expected push or pull", direction)
+	}
+
+	entries, err := client.listTree(ctx, repoType, repoID, revision, "", true, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	local := make(map[string]bool)
+	ignore := &IgnoreRules{}
+	if _, err := os.Stat(localDir); direction == SyncPush || !errors.Is(err, os.ErrNotExist) {
+		var files []string
+		if files, ignore, err = ListLocalFiles(localDir, nil); err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if !strings.HasSuffix(file, IncompleteSuffix) && !strings.HasSuffix(file, IncompleteSuffix+ETagSuffix) {
+				local[file] = true
+			}
+		}
+	}
+	var remote []HFFile
+	for _, file := range onlyFiles(entries) {
+		if !ignore.Ignored(file.Path, false) {
+			remote = append(remote, file)
+		}
+	}
+	report, err := client.compareLocalFiles(ctx, remote, local, localDir)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &SyncPlan{Direction: direction, Changed: report.Corrupted, Unchanged: len(report.Verified)}
+	onlyAtDest := report.Missing
+	if direction == SyncPush {
+		plan.New = report.Extra
+	} else {
+		plan.New, onlyAtDest = report.Missing, report.Extra
+	}
+	if delete {
+		for _, file := range onlyAtDest {
+			if direction == SyncPush && file == ".gitattributes" {
+				continue
+			}
+			plan.Deleted = append(plan.Deleted, file)
+		}
+	}
+	for _, files := range []*[]string{&plan.New, &plan.Changed, &plan.Deleted} {
+		if *files == nil {
+			*files = []string{}
+		}
+	}
+	return plan, nil
+}
@@ -0,0 +1,114 @@
+package apiv2
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// blobFile is the tree entry of a regular file with content.
+func blobFile(path, content string) HFFile {
+	return HFFile{Type: "file", Path: path, Size: uint(len(content)), Oid: gitBlobSHA1([]byte(content))}
+}
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPlanSync(t *testing.T) {
+	remote := []HFFile{
+		blobFile(".gitattributes", "*.bin filter=lfs\n"),
+		blobFile("same.txt", "same"),
+		blobFile("changed.txt", "old"),
+		blobFile("remote-only.txt", "remote"),
+		blobFile("logs/run.log", "remote log"),
+		{Type: "directory", Path: "logs"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/models/user/repo/tree/main" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(remote)
+	}))
+	defer server.Close()
+	client := NewHuggingFaceClient("", WithEndpoint(server.URL))
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".huggerignore":             "logs/\n*.tmp\n",
+		"same.txt":                  "same",
+		"changed.txt":               "new content",
+		"local-only.txt":            "local",
+		"scratch.tmp":               "ignored",
+		"logs/local.log":            "ignored",
+		"model.bin.incomplete":      "partial",
+		"model.bin.incomplete.etag": "etag",
+		".git/HEAD":                 "ref: refs/heads/main",
+	})
+
+	tests := []struct {
+		direction string
+		delete    bool
+		want      SyncPlan
+	}{
+		{SyncPush, false, SyncPlan{
+			Direction: SyncPush,
+			New:       []string{".huggerignore", "local-only.txt"},
+			Changed:   []string{"changed.txt"},
+			Deleted:   []string{},
+			Unchanged: 1,
+		}},
+		// .gitattributes is only on the Hub but never deleted from it, and
+		// the ignored logs/ are left alone
+		{SyncPush, true, SyncPlan{
+			Direction: SyncPush,
+			New:       []string{".huggerignore", "local-only.txt"},
+			Changed:   []string{"changed.txt"},
+			Deleted:   []string{"remote-only.txt"},
+			Unchanged: 1,
+		}},
+		{SyncPull, true, SyncPlan{
+			Direction: SyncPull,
+			New:       []string{".gitattributes", "remote-only.txt"},
+			Changed:   []string{"changed.txt"},
+			Deleted:   []string{".huggerignore", "local-only.txt"},
+			Unchanged: 1,
+		}},
+	}
+	for _, tt := range tests {
+		plan, err := client.PlanSync(tt.direction, "model", "user/repo", "", dir, tt.delete)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(*plan, tt.want) {
+			t.Errorf("PlanSync(%s, delete %v) = %+v, want %+v", tt.direction, tt.delete, *plan, tt.want)
+		}
+	}
+
+	// Pulling into a folder that does not exist yet downloads everything
+	plan, err := client.PlanSync(SyncPull, "model", "user/repo", "", filepath.Join(dir, "missing"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{".gitattributes", "same.txt", "changed.txt", "remote-only.txt", "logs/run.log"}
+	if !reflect.DeepEqual(plan.New, want) || len(plan.Deleted) != 0 {
+		t.Errorf("PlanSync(pull) into a missing folder = %+v, want %q new", plan, want)
+	}
+	if _, err := client.PlanSync("both", "model", "user/repo", "", dir, false); err == nil {
+		t.Errorf("PlanSync with an invalid direction returned no error")
+	}
+}
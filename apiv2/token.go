@@ -0,0 +1,222 @@
+package apiv2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenSource supplies the User Access Token of every request to the Hub.
+// It must be safe for concurrent use.
+type TokenSource interface {
+	// Token returns the token to send, or "" to access the Hub anonymously.
+	Token(ctx context.Context) (string, error)
+}
+
+// invalidator is implemented by sources that can drop a token the Hub
+// rejected, so that the request is retried once with a fresh one.
+type invalidator interface {
+	Invalidate()
+}
+
+// StaticToken is a TokenSource that always returns the same token.
+type StaticToken string
+
+func (t StaticToken) Token(context.Context) (string, error) {
+	return string(t), nil
+}
+
+// String hides the token when the source is printed.
+func (t StaticToken) String() string {
+	return RedactToken(string(t))
+}
+
+func (t StaticToken) GoString() string {
+	return fmt.Sprintf("apiv2.StaticToken(%q)", t.String())
+}
+
+// Anonymous sends no token at all; only public repositories are accessible.
+var Anonymous TokenSource = StaticToken("")
+
+// EnvToken reads the token from HF_TOKEN on every request.
+var EnvToken TokenSource = EnvVarToken(TokenEnv)
+
+// EnvVarToken reads the token from the environment variable it names on
+// every request.
+type EnvVarToken string
+
+func (name EnvVarToken) Token(context.Context) (string, error) {
+	return strings.TrimSpace(os.Getenv(string(name))), nil
+}
+
+// FileToken reads the token from a file such as TokenPath() on every
+// request. A missing file means no token.
+type FileToken string
+
+func (path FileToken) Token(context.Context) (string, error) {
+	data, err := os.ReadFile(string(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read token: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// ChainTokenSource returns the first token found in sources, in order.
+func ChainTokenSource(sources ...TokenSource) TokenSource {
+	return tokenChain(sources)
+}
+
+type tokenChain []TokenSource
+
+func (chain tokenChain) Token(ctx context.Context) (string, error) {
+	for _, source := range chain {
+		token, err := source.Token(ctx)
+		if err != nil || token != "" {
+			return token, err
+		}
+	}
+	return "", nil
+}
+
+// DefaultTokenSource looks for a token like huggingface_hub does: token if
+// it is set, then HF_TOKEN, then the token file written by login.
+func DefaultTokenSource(token string) TokenSource {
+	return ChainTokenSource(StaticToken(token), EnvToken, FileToken(TokenPath()))
+}
+
+// TokenRefreshFunc fetches a new token and the time it expires; a zero
+// expiry means the token does not expire.
+type TokenRefreshFunc func(ctx context.Context) (token string, expiry time.Time, err error)
+
+// tokenExpiryDelta is how long before its expiry a token is refreshed, so
+// that it does not expire in the middle of a request.
+const tokenExpiryDelta = time.Minute
+
+// RefreshingTokenSource caches the token of refresh until shortly before it
+// expires, or until the Hub rejects it with 401 Unauthorized.
+func RefreshingTokenSource(refresh TokenRefreshFunc) TokenSource {
+	return &refreshingToken{refresh: refresh}
+}
+
+type refreshingToken struct {
+	refresh TokenRefreshFunc
+	mu      sync.Mutex
+	token   string
+	expiry  time.Time
+}
+
+func (t *refreshingToken) Token(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token != "" && (t.expiry.IsZero() || time.Until(t.expiry) > tokenExpiryDelta) {
+		return t.token, nil
+	}
+	token, expiry, err := t.refresh(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to refresh token: %w", err)
+	}
+	t.token, t.expiry = token, expiry
+	return token, nil
+}
+
+func (t *refreshingToken) Invalidate() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.token = ""
+}
+
+// WithTokenSource authenticates every request with the tokens of source.
+func WithTokenSource(source TokenSource) ClientOption {
+	return func(client *HuggingFaceClient) {
+		client.TokenSource = source
+	}
+}
+
+// WithToken authenticates every request with token.
+func WithToken(token string) ClientOption {
+	return WithTokenSource(StaticToken(token))
+}
+
+// tokenSource returns TokenSource, or else the deprecated Token and APIKey fields.
+func (client *HuggingFaceClient) tokenSource() TokenSource {
+	switch {
+	case client.TokenSource != nil:
+		return client.TokenSource
+	case client.Token != "":
+		return StaticToken(client.Token)
+	case client.APIKey != "":
+		return StaticToken(client.APIKey)
+	}
+	return Anonymous
+}
+
+// authorize sets the Authorization header of a request to the Hub. It is
+// the only place that reads the client's token; requests to LFS storage
+// carry their own credentials and must not go through it.
+func (client *HuggingFaceClient) authorize(req *http.Request) error {
+	token, err := client.tokenSource().Token(req.Context())
+	if err != nil {
+		return redactError(err)
+	}
+	if token == "" {
+		req.Header.Del("Authorization")
+		return nil
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// invalidateToken drops the token of the client after a 401, and reports
+// whether a new one may be worth a retry.
+func (client *HuggingFaceClient) invalidateToken() bool {
+	source, ok := client.tokenSource().(invalidator)
+	if ok {
+		source.Invalidate()
+	}
+	return ok
+}
+
+// tokenPattern matches User Access Tokens, hf_ and at least 30 letters and
+// digits, so that names like hf_hub or hf_transfer are left alone, and the
+// credentials of Authorization headers.
+var tokenPattern = regexp.MustCompile(`hf_[A-Za-z0-9]{30,}|(?i:bearer\s+)[^\s"',]+`)
+
+// RedactToken masks every User Access Token and bearer credential in s,
+// keeping the hf_ prefix so that messages still tell which kind it was.
+func RedactToken(s string) string {
+	return tokenPattern.ReplaceAllStringFunc(s, func(token string) string {
+		if strings.HasPrefix(token, "hf_") {
+			return "hf_***"
+		}
+		return strings.Fields(token)[0] + " ***"
+	})
+}
+
+// redactedError hides tokens in the message of err but keeps it for errors.Is.
+type redactedError struct {
+	err error
+}
+
+func redactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &redactedError{err}
+}
+
+func (e *redactedError) Error() string {
+	return RedactToken(e.err.Error())
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,123 @@
+package apiv2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedactToken(t *testing.T) {
+	token := "hf_" + strings.Repeat("aB3", 12)
+	tests := []struct {
+		in, want string
+	}{
+		{"no token here", "no token here"},
+		{"token " + token + " rejected", "token hf_*** rejected"},
+		{`{"token":"` + token + `"}`, `{"token":"hf_***"}`},
+		{"Authorization: Bearer abc.def-ghi", "Authorization: Bearer ***"},
+		{`"authorization":"bearer secret","x":1`, `"authorization":"bearer ***","x":1`},
+		// Names of tools and short ids are not tokens
+		{"install hf_transfer and hf_hub", "install hf_transfer and hf_hub"},
+		{"hf_short123", "hf_short123"},
+	}
+	for _, tt := range tests {
+		if got := RedactToken(tt.in); got != tt.want {
+			t.Errorf("RedactToken(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestStaticTokenString(t *testing.T) {
+	token := StaticToken("hf_" + strings.Repeat("x", 34))
+	for _, s := range []string{fmt.Sprint(token), fmt.Sprintf("%v", token), fmt.Sprintf("%#v", token)} {
+		if strings.Contains(s, string(token)) {
+			t.Errorf("printed token %q is not redacted", s)
+		}
+	}
+}
+
+func TestChainTokenSource(t *testing.T) {
+	t.Setenv(TokenEnv, " hf_env \n")
+	tests := []struct {
+		sources []TokenSource
+		want    string
+	}{
+		{[]TokenSource{StaticToken("hf_flag"), EnvToken}, "hf_flag"},
+		{[]TokenSource{StaticToken(""), EnvToken}, "hf_env"},
+		{[]TokenSource{Anonymous, EnvVarToken("HUGGER_TEST_UNSET")}, ""},
+		{[]TokenSource{FileToken("/nonexistent/token"), EnvToken}, "hf_env"},
+	}
+	for i, tt := range tests {
+		got, err := ChainTokenSource(tt.sources...).Token(context.Background())
+		if err != nil || got != tt.want {
+			t.Errorf("chain %d = %q, %v, want %q", i, got, err, tt.want)
+		}
+	}
+}
+
+func TestAuthorizePage(t *testing.T) {
+	// The next page lives on another host, which must not get the token
+	var foreignAuth string
+	foreign := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		foreignAuth = r.Header.Get("Authorization")
+		json.NewEncoder(w).Encode([]HFFile{{Type: "file", Path: "b.txt"}})
+	}))
+	defer foreign.Close()
+
+	var hubAuth string
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hubAuth = r.Header.Get("Authorization")
+		w.Header().Set("Link", fmt.Sprintf(`<%s/page2>; rel="next"`, foreign.URL))
+		json.NewEncoder(w).Encode([]HFFile{{Type: "file", Path: "a.txt"}})
+	}))
+	defer hub.Close()
+
+	client := NewHuggingFaceClient("hf_test", WithEndpoint(hub.URL))
+	files, err := client.ListFilesInRepo("model", "user/repo", "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("ListFilesInRepo = %+v, want both pages", files)
+	}
+	if hubAuth != "Bearer hf_test" {
+		t.Errorf("Authorization sent to the Hub = %q, want the token", hubAuth)
+	}
+	if foreignAuth != "" {
+		t.Errorf("Authorization sent to another host = %q, want none", foreignAuth)
+	}
+}
+
+func TestAuthorizeDatasetsServer(t *testing.T) {
+	tests := []struct {
+		name             string
+		endpoint, server string
+		want             bool
+	}{
+		{"default Hub and datasets-server", "", DefaultDatasetsServerEndpoint, true},
+		{"same host", "https://hub.example", "https://hub.example/datasets-server", true},
+		{"mirror with the public datasets-server", "https://hub.example", DefaultDatasetsServerEndpoint, false},
+		{"default Hub with another datasets-server", "", "https://stats.example", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EndpointEnv, "")
+			opts := []ClientOption{WithDatasetsServerEndpoint(tt.server)}
+			if tt.endpoint != "" {
+				opts = append(opts, WithEndpoint(tt.endpoint))
+			}
+			client := NewHuggingFaceClient("hf_test", opts...)
+			req, _ := http.NewRequest("GET", client.datasetsServerEndpoint()+"/statistics", nil)
+			if err := client.authorizeDatasetsServer(req); err != nil {
+				t.Fatal(err)
+			}
+			if got := req.Header.Get("Authorization") != ""; got != tt.want {
+				t.Errorf("token sent = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package apiv2
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// ProgressFunc receives how many bytes of pathInRepo have been transferred
+// so far out of total, which is 0 while the size is unknown. The count may go
+// back when a transfer starts over. It is called from several goroutines at
+// once when transfers run concurrently.
+type ProgressFunc func(pathInRepo string, transferred, total int64)
+
+// WithProgress reports the bytes transferred by downloads and LFS uploads to fn.
+func WithProgress(fn ProgressFunc) ClientOption {
+	return func(client *HuggingFaceClient) {
+		client.Progress = fn
+	}
+}
+
+// WithConcurrency transfers up to n files at once.
+func WithConcurrency(n int) ClientOption {
+	return func(client *HuggingFaceClient) {
+		client.Concurrency = n
+	}
+}
+
+func (client *HuggingFaceClient) concurrency() int {
+	if client.Concurrency < 1 {
+		return 1
+	}
+	return client.Concurrency
+}
+
+func (client *HuggingFaceClient) reportProgress(pathInRepo string, transferred, total int64) {
+	if client.Progress != nil {
+		client.Progress(pathInRepo, transferred, total)
+	}
+}
+
+// TransferFailure is a file of a batch that could not be transferred.
+type TransferFailure struct {
+	Path string
+	Err  error
+}
+
+// TransferError is returned when some files of a batch failed. The other
+// files were transferred all the same.
+type TransferError struct {
+	Total    int
+	Failures []TransferFailure
+}
+
+func (e *TransferError) Error() string {
+	if len(e.Failures) == 1 {
+		return fmt.Sprintf("%s: %v", e.Failures[0].Path, e.Failures[0].Err)
+	}
+	return fmt.Sprintf("%d of %d transfers failed", len(e.Failures), e.Total)
+}
+
+// Unwrap gives access to the error of every failure, so that errors.Is
+// tells e.g. that one of the files was not found.
+func (e *TransferError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f.Err
+	}
+	return errs
+}
+
+// forEachParallel calls fn for every path with up to concurrency calls at
+// once. A failure does not stop the others; they are all returned in a
+// *TransferError. When ctx is cancelled no new call starts and ctx's error
+// is returned.
+func forEachParallel(ctx context.Context, concurrency int, paths []string, fn func(i int, path string) error) error {
+	errs := make([]error, len(paths))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, path := range paths {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = fn(i, path)
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var failures []TransferFailure
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, TransferFailure{Path: paths[i], Err: err})
+		}
+	}
+	if len(failures) > 0 {
+		return &TransferError{Total: len(paths), Failures: failures}
+	}
+	return nil
+}
+
+// progressWriter counts the bytes written through it.
+type progressWriter struct {
+	w      io.Writer
+	n      int64
+	report func(n int64)
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	pw.n += int64(n)
+	pw.report(pw.n)
+	return n, err
+}
+
+// progressReader reports every byte read through it.
+type progressReader struct {
+	r    io.Reader
+	sent *int64
+	add  func(delta int64)
+}
+
+func (pr progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	if n > 0 {
+		atomic.AddInt64(pr.sent, int64(n))
+		pr.add(int64(n))
+	}
+	return n, err
+}
@@ -0,0 +1,93 @@
+package apiv2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var linkNextRegexp = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="?next"?`)
+
+// nextPageURL returns the URL of the next page of a paginated answer, from
+// its Link header, or "" on the last page.
+func nextPageURL(header http.Header) string {
+	for _, link := range header.Values("Link") {
+		if m := linkNextRegexp.FindStringSubmatch(link); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// authorizePage authorizes the request of a page of a paginated answer only
+// if it goes to the Hub, with the scheme and host of its endpoint, so that a
+// Link header never gets the token sent elsewhere.
+func (client *HuggingFaceClient) authorizePage(req *http.Request) error {
+	hub, err := url.Parse(client.endpoint())
+	if err != nil || req.URL.Scheme != hub.Scheme || req.URL.Host != hub.Host {
+		req.Header.Del("Authorization")
+		return nil
+	}
+	return client.authorize(req)
+}
+
+// listTree returns the entries under path at revision, following the Link
+// header from page to page. With recursive the entries of every folder below
+// are included, with expand the last commit of every entry is filled in.
+func (client *HuggingFaceClient) listTree(ctx context.Context, repoType, repoID, revision, path string, recursive, expand bool) ([]HFFile, error) {
+	endpoint := fmt.Sprintf("%s/api/%s/%s/tree/%s", client.endpoint(), repoType+"s", repoID, escapeRevision(revision))
+	if p := strings.Trim(path, "/"); p != "" {
+		endpoint += "/" + escapeRepoPath(p)
+	}
+	query := url.Values{}
+	if recursive {
+		query.Set("recursive", "true")
+	}
+	if expand {
+		query.Set("expand", "true")
+	}
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var entries []HFFile
+	for endpoint != "" {
+		req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create tree request: %w", err)
+		}
+		if err := client.authorizePage(req); err != nil {
+			return nil, err
+		}
+
+		resp, err := client.doRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		var page []HFFile
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode tree of %s: %w", repoID, err)
+		}
+
+		entries = append(entries, page...)
+		endpoint = nextPageURL(resp.Header)
+	}
+	return entries, nil
+}
+
+// onlyFiles drops the directories of a tree listing.
+func onlyFiles(entries []HFFile) []HFFile {
+	var files []HFFile
+	for _, entry := range entries {
+		if entry.Type == "file" {
+			files = append(files, entry)
+		}
+	}
+	return files
+}
@@ -76,18 +76,18 @@ func UpdateApp() error {
 
 	latestVersion, err := checkForUpdate()
 	if err != nil {
-		fmt.Println("Error checking for updates:", err)
+		fmt.Fprintln(os.Stderr, "Error checking for updates:", err)
 		return err
 	}
 
 	if latestVersion != currentVersion {
-		fmt.Printf("New version available: %s (current: %s)\n", latestVersion, currentVersion)
+		fmt.Fprintf(os.Stderr, "New version available: %s (current: %s)\n", latestVersion, currentVersion)
 		err = downloadUpdate(latestVersion)
 		if err != nil {
-			fmt.Println("Error downloading update:", err)
+			fmt.Fprintln(os.Stderr, "Error downloading update:", err)
 			return err
 		}
-		fmt.Println("Update downloaded. Restarting application...")
+		fmt.Fprintln(os.Stderr, "Update downloaded. Restarting application...")
 
 		// Restart the application
 		if err := exec.Command(executable).Start(); err != nil {
@@ -0,0 +1,135 @@
+package apiv2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// VerifyReport tells how a local folder differs from a revision of a repository.
+type VerifyReport struct {
+	// Verified files have the content they have in the repository.
+	Verified []string `json:"verified"`
+	// Missing files are in the repository but not in the folder.
+	Missing []string `json:"missing"`
+	// Extra files are in the folder but not in the repository.
+	Extra []string `json:"extra"`
+	// Corrupted files differ in size or checksum from the repository's.
+	Corrupted []string `json:"corrupted"`
+}
+
+// OK reports whether the folder holds exactly the files of the repository.
+func (r *VerifyReport) OK() bool {
+	return len(r.Missing) == 0 && len(r.Extra) == 0 && len(r.Corrupted) == 0
+}
+
+var errSizeMismatch = errors.New("size mismatch")
+
+// VerifyLocalDir checks every file of localDir against the size and checksum
+// (git blob SHA-1, or sha256 for LFS files) of the same path in the repository
+// at revision. Files are hashed up to the client's Concurrency at once. The
+// .git folder is ignored.
+func (client *HuggingFaceClient) VerifyLocalDir(repoType, repoID, revision, localDir string) (*VerifyReport, error) {
+	return client.VerifyLocalDirContext(context.Background(), repoType, repoID, revision, localDir)
+}
+
+func (client *HuggingFaceClient) VerifyLocalDirContext(ctx context.Context, repoType, repoID, revision, localDir string) (*VerifyReport, error) {
+	entries, err := client.listTree(ctx, repoType, repoID, revision, "", true, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	local, err := localFiles(localDir)
+	if err != nil {
+		return nil, err
+	}
+	return client.compareLocalFiles(ctx, onlyFiles(entries), local, localDir)
+}
+
+// localFiles returns the slash-separated paths of the files below localDir,
+// but those of the .git folder.
+func localFiles(localDir string) (map[string]bool, error) {
+	local := make(map[string]bool)
+	err := filepath.WalkDir(localDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(localDir, path)
+		if err != nil {
+			return err
+		}
+		local[filepath.ToSlash(rel)] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", localDir, err)
+	}
+	return local, nil
+}
+
+// compareLocalFiles sorts the remote files and the local ones, which it
+// takes away from local, into a VerifyReport.
+func (client *HuggingFaceClient) compareLocalFiles(ctx context.Context, remote []HFFile, local map[string]bool, localDir string) (*VerifyReport, error) {
+	report := &VerifyReport{}
+	var present []HFFile
+	var paths []string
+	for _, file := range remote {
+		if !local[file.Path] {
+			report.Missing = append(report.Missing, file.Path)
+			continue
+		}
+		delete(local, file.Path)
+		present = append(present, file)
+		paths = append(paths, file.Path)
+	}
+	for path := range local {
+		report.Extra = append(report.Extra, path)
+	}
+	sort.Strings(report.Extra)
+
+	err := forEachParallel(ctx, client.concurrency(), paths, func(i int, pathInRepo string) error {
+		want := fileChecksum(present[i])
+		if want == nil {
+			return nil
+		}
+		path := filepath.Join(localDir, filepath.FromSlash(pathInRepo))
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if info.Size() != want.size {
+			return errSizeMismatch
+		}
+		return want.verifyFile(pathInRepo, path)
+	})
+
+	failed := make(map[string]bool)
+	var transferErr *TransferError
+	if errors.As(err, &transferErr) {
+		for _, f := range transferErr.Failures {
+			if !errors.Is(f.Err, ErrChecksumMismatch) && !errors.Is(f.Err, errSizeMismatch) {
+				return nil, fmt.Errorf("failed to verify %s: %w", f.Path, f.Err)
+			}
+			failed[f.Path] = true
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		if failed[path] {
+			report.Corrupted = append(report.Corrupted, path)
+		} else {
+			report.Verified = append(report.Verified, path)
+		}
+	}
+	return report, nil
+}
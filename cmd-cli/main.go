@@ -1,57 +1,123 @@
 package main
 
 import (
-	"encoding/json"
+	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"io/fs"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
 	api "hugger/apiv2"
 	huggerLog "hugger/log"
 	"github.com/fatih/color"
+	"golang.org/x/term"
 )
 
-type HError struct {
-	Error string `json:"error"`
-}
+// Exit codes, so that scripts can tell failures apart. 2 is left to the flag package.
+const (
+	exitFailure      = 1
+	exitUnauthorized = 3
+	exitNotFound     = 4
+	exitGated        = 5
+	exitRateLimited  = 6
+	exitConflict     = 7
+	exitChecksum     = 8
+	exitInterrupted  = 130
+)
+
+// defaultConcurrency is how many files download, snapshot and upload transfer at once.
+const defaultConcurrency = 4
+
+// profile holds the defaults of the -profile given before the subcommand.
+var profile = &api.Profile{}
 
 func main() {
 	// Check for updates
 	api.UpdateApp()
 
-	if len(os.Args) < 2 {
+	global := flag.NewFlagSet("hugger", flag.ExitOnError)
+	global.Usage = printHelp
+	profileName := global.String("profile", os.Getenv(api.ProfileEnv), "Profile of the config file to use")
+	global.Parse(os.Args[1:])
+	args := global.Args()
+
+	if len(args) < 1 {
 		printHelp()
 		os.Exit(1)
 	}
+	if args[0] != "config" {
+		config, err := api.LoadConfig(api.ConfigPath())
+		if err == nil {
+			profile, err = config.Profile(*profileName)
+		}
+		if err != nil {
+			huggerLog.Error(err.Error())
+			os.Exit(exitFailure)
+		}
+		if profile.Theme == api.ThemePlain {
+			huggerLog.NoColor = true
+			color.NoColor = true
+		}
+	}
+
+	// Ctrl-C cancels in-flight transfers instead of killing the process mid-write
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
 	// Handle subcommands
-	switch os.Args[1] {
+	switch args[0] {
 	case "help", "-h", "--help":
 		printHelp()
 	case "download":
-		handleDownload()
+		handleDownload(ctx, args[1:])
+	case "snapshot":
+		handleSnapshot(ctx, args[1:])
+	case "verify":
+		handleVerify(ctx, args[1:])
 	case "upload":
-		handleUpload()
+		handleUpload(ctx, args[1:])
+	case "sync":
+		handleSync(ctx, args[1:])
 	case "repo":
-		handleRepo()
+		handleRepo(ctx, args[1:])
 	case "repo-files":
-		handleRepoFiles()
+		handleRepoFiles(ctx, args[1:])
+	case "refs":
+		handleRefs(ctx, args[1:])
+	case "log":
+		handleLog(ctx, args[1:])
+	case "discussions":
+		handleDiscussions(ctx, args[1:])
 	case "meta":
-		handleMeta()
+		handleMeta(ctx, args[1:])
 	case "statistics":
-		handleStatistics()
+		handleStatistics(ctx, args[1:])
+	case "login":
+		handleLogin(ctx, args[1:])
+	case "logout":
+		handleLogout(ctx, args[1:])
+	case "whoami":
+		handleWhoAmI(ctx, args[1:])
+	case "config":
+		handleConfig(ctx, *profileName, args[1:])
 	default:
-		fmt.Printf("Unknown subcommand: %s\n", os.Args[1])
+		fmt.Printf("Unknown subcommand: %s\n", args[0])
 		os.Exit(1)
 	}
 }
 
 func printHelp() {
 	Banner()
+	fmt.Println("Usage: hugger [-profile name] <subcommand> [arguments]")
+	fmt.Println()
 	fmt.Println("Available subcommands:")
 	fmt.Println("  help                Show this help message")
 	fmt.Println("  download            Download files from a repository")
@@ -59,22 +125,87 @@ func printHelp() {
 	fmt.Println("      -repo-id        Repository ID")
 	fmt.Println("      -filenames      Comma-separated list of filenames")
 	fmt.Println("      -repo-type      Type of the repository")
-	fmt.Println("      -token          A User Access Token generated from https://huggingface.co/settings/tokens")
+	fmt.Println("      -revision       Branch, tag or commit hash (default: main)")
+	fmt.Println("      -no-cache       Download directly, without the cache shared with huggingface_hub")
+	fmt.Println("      -concurrency    Number of files to download at once (default: 4)")
+	fmt.Println("      -connections    Download large files in that many parallel chunks (default: 1)")
+	fmt.Println("      -token          A User Access Token (default: $HF_TOKEN, then the token saved by login)")
+	fmt.Println("      -output         Output format: table, json, yaml or csv (default: table)")
+	fmt.Println()
+	fmt.Println("  snapshot            Download the files of a repository matching glob patterns")
+	fmt.Println("    Arguments:")
+	fmt.Println("      -repo-id        Repository ID")
+	fmt.Println("      -repo-type      Type of the repository")
+	fmt.Println("      -revision       Branch, tag or commit hash (default: main)")
+	fmt.Println("      -include        Comma-separated glob patterns of files to download (default: all)")
+	fmt.Println("      -exclude        Comma-separated glob patterns of files to skip")
+	fmt.Println("      -local-dir      Directory to download to (default: the cache only)")
+	fmt.Println("      -concurrency    Number of files to download at once (default: 4)")
+	fmt.Println("      -connections    Download large files in that many parallel chunks (default: 1)")
+	fmt.Println("      -token          A User Access Token (default: $HF_TOKEN, then the token saved by login)")
+	fmt.Println("      -output         Output format: table, json, yaml or csv (default: table)")
 	fmt.Println()
-	fmt.Println("  upload              Upload files to a repository")
+	fmt.Println("  verify              Check a local folder against a repository: missing, extra and corrupted files")
+	fmt.Println("    Arguments:")
+	fmt.Println("      -repo-id        Repository ID")
+	fmt.Println("      -repo-type      Type of the repository")
+	fmt.Println("      -revision       Branch, tag or commit hash (default: main)")
+	fmt.Println("      -local-dir      Directory to check (default: current directory)")
+	fmt.Println("      -concurrency    Number of files to hash at once (default: 4)")
+	fmt.Println("      -token          A User Access Token (default: $HF_TOKEN, then the token saved by login)")
+	fmt.Println("      -output         Output format: table, json, yaml or csv (default: table)")
+	fmt.Println()
+	fmt.Println("  upload              Upload files to a repository in a single commit")
 	fmt.Println("    Arguments:")
 	fmt.Println("      -repo-id        Repository ID")
 	fmt.Println("      -filenames      Comma-separated list of filenames")
 	fmt.Println("      -repo-type      Type of the repository")
-	fmt.Println("      -token          A User Access Token generated from https://huggingface.co/settings/tokens")
+	fmt.Println("      -revision       Branch to commit to, or refs/pr/N to add to a pull request (default: main)")
+	fmt.Println("      -create-pr      Open a pull request against the branch instead of committing to it")
+	fmt.Println("      -include        Comma-separated glob patterns of files to upload (default: all)")
+	fmt.Println("      -exclude        Comma-separated glob patterns of files to skip")
+	fmt.Println("      -dry-run        Only list the files to upload")
+	fmt.Println("      -local-root     Folder the paths in the repository are relative to (default: current directory)")
+	fmt.Println("      -path-in-repo   Folder of the repository to upload to (default: its root)")
+	fmt.Println("      -concurrency    Number of files to upload at once (default: 4)")
+	fmt.Println("      -token          A User Access Token (default: $HF_TOKEN, then the token saved by login)")
+	fmt.Println("      -output         Output format: table, json, yaml or csv (default: table)")
+	fmt.Println("      -commit-message       Summary of the commit")
+	fmt.Println("      -commit-description   Description of the commit")
+	fmt.Println("    Folders are uploaded without their .git folder and the files their .huggerignore")
+	fmt.Println("    files ignore, in gitignore syntax. -include and -exclude match the paths in the repository.")
+	fmt.Println()
+	fmt.Println("  sync                Make a repository match a local folder, or the other way round")
+	fmt.Println("    Usage:")
+	fmt.Println("      sync push <dir>              Upload the new and changed files of dir in one commit")
+	fmt.Println("      sync pull <dir>              Download the new and changed files of the repository to dir")
+	fmt.Println("    Arguments:")
+	fmt.Println("      -repo-id        Repository ID")
+	fmt.Println("      -repo-type      Type of the repository")
+	fmt.Println("      -revision       Branch, tag or commit hash (default: main)")
+	fmt.Println("      -delete         Also delete the files missing from the source, after confirmation")
+	fmt.Println("      -yes            Delete without asking for confirmation, e.g. when stdin is not a terminal")
+	fmt.Println("      -dry-run        Only show the planned changes")
+	fmt.Println("      -concurrency    Number of files to hash and transfer at once (default: 4)")
+	fmt.Println("      -token          A User Access Token (default: $HF_TOKEN, then the token saved by login)")
+	fmt.Println("      -output         Output format: table, json, yaml or csv (default: table)")
+	fmt.Println("      -commit-message       push: summary of the commit")
+	fmt.Println("      -commit-description   push: description of the commit")
+	fmt.Println("      -create-pr            push: open a pull request instead of committing to the branch")
 	fmt.Println()
 	fmt.Println("  repo                Perform actions on repository")
 	fmt.Println("    Arguments:")
 	fmt.Println("      -repo-id        Repository ID")
 	fmt.Println("      -repo-type      Type of the repository")
-	fmt.Println("      -action         Action to perform on repo files ({delete,create})")
-	fmt.Println("      -token          A User Access Token generated from https://huggingface.co/settings/tokens")
+	fmt.Println("      -action         Action to perform on repo ({create,delete,settings,move})")
+	fmt.Println("      -token          A User Access Token (default: $HF_TOKEN, then the token saved by login)")
+	fmt.Println("      -output         Output format: table, json, yaml or csv (default: table)")
 	fmt.Println("      -private        Create/delete private repository")
+	fmt.Println("      -visibility     settings: make the repository public or private")
+	fmt.Println("      -gated          settings: gated access with auto or manual approval, or false to turn it off")
+	fmt.Println("      -to-repo-id     move: new repository ID, e.g. to rename it or move it to an organization")
+	fmt.Println("      -dry-run        move: only show what would be moved (default: true)")
+	fmt.Println("      -yes            move: do not ask for confirmation")
 	fmt.Println()
 	fmt.Println("  repo-files          Perform actions on repository files")
 	fmt.Println("    Arguments:")
@@ -82,135 +213,764 @@ func printHelp() {
 	fmt.Println("      -repo-type      Type of the repository")
 	fmt.Println("      -action         Action to perform on repo files ({delete,list})")
 	fmt.Println("      -file           File to do action with. Optionally, you can pass a directory name here")
-	fmt.Println("      -token          A User Access Token generated from https://huggingface.co/settings/tokens")
+	fmt.Println("      -revision       Branch, tag or commit hash (default: main)")
+	fmt.Println("      -token          A User Access Token (default: $HF_TOKEN, then the token saved by login)")
+	fmt.Println("      -output         Output format: table, json, yaml or csv (default: table)")
+	fmt.Println()
+	fmt.Println("  refs                List, create and delete the branches and tags of a repository")
+	fmt.Println("    Arguments:")
+	fmt.Println("      -repo-id        Repository ID")
+	fmt.Println("      -repo-type      Type of the repository")
+	fmt.Println("      -action         Action to perform ({list,create-branch,delete-branch,create-tag,delete-tag}, default: list)")
+	fmt.Println("      -name           Name of the branch or tag to create or delete")
+	fmt.Println("      -revision       Revision to create the branch from or to tag (default: main)")
+	fmt.Println("      -message        Message of the tag")
+	fmt.Println("      -token          A User Access Token (default: $HF_TOKEN, then the token saved by login)")
+	fmt.Println("      -output         Output format: table, json, yaml or csv (default: table)")
+	fmt.Println()
+	fmt.Println("  log                 Show the commit history of a repository")
+	fmt.Println("    Usage:")
+	fmt.Println("      log                          List the commits, newest first")
+	fmt.Println("      log show <commit>            Show a commit and the files it touched")
+	fmt.Println("    Arguments:")
+	fmt.Println("      -repo-id        Repository ID")
+	fmt.Println("      -repo-type      Type of the repository")
+	fmt.Println("      -revision       Branch, tag or commit hash to list the history of (default: main)")
+	fmt.Println("      -path           Only list the commits that touched this file or folder")
+	fmt.Println("      -limit          Maximum number of commits to list, 0 for all (default: 20)")
+	fmt.Println("      -token          A User Access Token (default: $HF_TOKEN, then the token saved by login)")
+	fmt.Println("      -output         Output format: table, json, yaml or csv (default: table)")
+	fmt.Println()
+	fmt.Println("  discussions         List, view and take part in the discussions and pull requests of a repository")
+	fmt.Println("    Usage:")
+	fmt.Println("      discussions [list]           List the discussions and pull requests")
+	fmt.Println("      discussions view <num>       Show a discussion and its comments")
+	fmt.Println("      discussions open -title t    Open a discussion, or a pull request with -pr")
+	fmt.Println("      discussions open <num>       Reopen a discussion")
+	fmt.Println("      discussions comment <num>    Post -message as a comment")
+	fmt.Println("      discussions close <num>      Close a discussion")
+	fmt.Println("      discussions merge <num>      Merge a pull request")
+	fmt.Println("    Arguments:")
+	fmt.Println("      -repo-id        Repository ID")
+	fmt.Println("      -repo-type      Type of the repository")
+	fmt.Println("      -status         list: open, closed or all (default: all)")
+	fmt.Println("      -type           list: discussion, pull_request or all (default: all)")
+	fmt.Println("      -author         list: only the discussions opened by this user")
+	fmt.Println("      -title          open: title of the new discussion")
+	fmt.Println("      -pr             open: open a pull request, whose refs/pr/N revision uploads can target")
+	fmt.Println("      -message        Description of a new discussion, or comment to post")
+	fmt.Println("      -token          A User Access Token (default: $HF_TOKEN, then the token saved by login)")
+	fmt.Println("      -output         Output format: table, json, yaml or csv (default: table)")
 	fmt.Println()
 	fmt.Println("  meta                Show meta information about repository")
 	fmt.Println("    Arguments:")
 	fmt.Println("      -repo-id        Repository ID")
 	fmt.Println("      -repo-type      Type of repository")
-	fmt.Println("      -token          A User Access Token generated from https://huggingface.co/settings/tokens")
+	fmt.Println("      -revision       Branch, tag or commit hash (default: main)")
+	fmt.Println("      -token          A User Access Token (default: $HF_TOKEN, then the token saved by login)")
+	fmt.Println("      -output         Output format: table, json, yaml or csv (default: table)")
 	fmt.Println()
 	fmt.Println("  statistics          Show statistics for specified repository. Dataset-only feature")
 	fmt.Println("    Arguments:")
 	fmt.Println("      -repo-id        Repository ID")
 	fmt.Println("      -split          Dataset split (e.g. train)")
+	fmt.Println("      -token          A User Access Token (default: $HF_TOKEN, then the token saved by login)")
+	fmt.Println("      -output         Output format: table, json, yaml or csv (default: table)")
+	fmt.Println()
+	fmt.Println("  login               Check a User Access Token and save it for the other subcommands")
+	fmt.Println("    Arguments:")
 	fmt.Println("      -token          A User Access Token generated from https://huggingface.co/settings/tokens")
+	fmt.Println("                      (default: read from the terminal or stdin, so it stays out of the shell history)")
+	fmt.Println()
+	fmt.Println("  logout              Remove the saved token")
+	fmt.Println()
+	fmt.Println("  whoami              Show the account of the current token")
+	fmt.Println("    Arguments:")
+	fmt.Println("      -token          A User Access Token (default: $HF_TOKEN, then the token saved by login)")
+	fmt.Println("      -output         Output format: table, json, yaml or csv (default: table)")
+	fmt.Println()
+	fmt.Println("  config              Show or change the settings of the profiles in ~/.config/hugger/config.yaml")
+	fmt.Println("    Usage:")
+	fmt.Println("      config list                  List every setting of every profile")
+	fmt.Println("      config get <setting>         Show a setting of the profile; a token itself is shown as ***")
+	fmt.Println("      config set <setting> <value> Change a setting of the profile; an empty value removes it")
+	fmt.Println("    Settings:         endpoint, token (env:NAME, file:PATH or the token), repo-type, concurrency,")
+	fmt.Println("                      output, theme (dark, light or plain) and default-profile")
+	fmt.Println()
+	fmt.Println("Global arguments, before the subcommand:")
+	fmt.Println("  -profile            Profile of the config file to use (default: $HUGGER_PROFILE, then default-profile)")
+	fmt.Println()
+	fmt.Println("Exit codes:")
+	fmt.Println("  1                   Generic failure")
+	fmt.Println("  3                   Unauthorized (missing or invalid token)")
+	fmt.Println("  4                   Repository, revision or file not found")
+	fmt.Println("  5                   Gated repository, access not granted yet")
+	fmt.Println("  6                   Rate limited by the Hub")
+	fmt.Println("  7                   Conflict (e.g. repository already exists)")
+	fmt.Println("  8                   Checksum mismatch (corrupted download or local file)")
+	fmt.Println("  130                 Interrupted with Ctrl-C")
 	fmt.Println()
 }
 
-func handleMeta() {
+func handleMeta(ctx context.Context, args []string) {
 	metaf := flag.NewFlagSet("meta", flag.ExitOnError)
 	repoID := metaf.String("repo-id", "", "Repository ID")
-	repoType := metaf.String("repo-type", "", "Type of the repository")
+	repoType := repoTypeFlag(metaf)
 	token := metaf.String("token", "", "User Access Token")
+	output := outputFlag(metaf)
+	revision := metaf.String("revision", "", "Branch, tag or commit hash")
 
-	metaf.Parse(os.Args[2:])
+	metaf.Parse(args)
+	checkOutput(*output)
 
-	if *repoID == "" || *repoType == "" || *token == "" {
-		fmt.Println("meta subcommand requires repo-id, repo-type, and token arguments")
+	if *repoID == "" || *repoType == "" {
+		fmt.Println("meta subcommand requires repo-id and repo-type arguments")
 		os.Exit(1)
 	}
 
-	if err := api.ServeRequest("meta", *repoID, *repoType, *token, "", "", nil, false); err != nil {
-		handleError(err)
+	req := api.Request{
+		Type:     "meta",
+		RepoID:   *repoID,
+		RepoType: *repoType,
+		Revision: *revision,
+		Token:    *token,
+		Output:   *output,
 	}
+	serve(ctx, req)
 }
 
-func handleStatistics() {
+func handleStatistics(ctx context.Context, args []string) {
 	stat := flag.NewFlagSet("statistics", flag.ExitOnError)
 	repoID := stat.String("repo-id", "", "Repository ID")
 	split := stat.String("split", "", "Dataset split(e.g. train)")
 	token := stat.String("token", "", "User Access Token")
+	output := outputFlag(stat)
 
-	stat.Parse( os.Args[2:] )
-	if *repoID == "" || *token == "" || *split == "" {
-		fmt.Println("statistics subcommand requires repo-id, split and token arguments")
+	stat.Parse( args )
+	checkOutput(*output)
+	if *repoID == "" || *split == "" {
+		fmt.Println("statistics subcommand requires repo-id and split arguments")
 		os.Exit(1)
 	}
-	if err := api.ServeRequest( "statistics", *repoID, "dataset", *token, "", *split, nil, false ); err != nil {
-		handleError(err)
+	req := api.Request{
+		Type:     "statistics",
+		RepoID:   *repoID,
+		RepoType: "dataset",
+		Token:    *token,
+		Split:    *split,
+		Output:   *output,
 	}
+	serve(ctx, req)
 }
 
-func handleDownload() {
+func handleDownload(ctx context.Context, args []string) {
 	download := flag.NewFlagSet("download", flag.ExitOnError)
 	repoID := download.String("repo-id", "", "Repository ID")
 	filenames := download.String("filenames", "", "Comma-separated list of filenames")
-	repoType := download.String("repo-type", "", "Type of the repository")
+	repoType := repoTypeFlag(download)
 	token := download.String("token", "", "User Access Token")
+	output := outputFlag(download)
+	concurrency := concurrencyFlag(download, "Number of files to transfer at once")
+	connections := download.Int("connections", 1, "Number of parallel connections for each large file")
+	revision := download.String("revision", "", "Branch, tag or commit hash")
+	noCache := download.Bool("no-cache", false, "Do not use the local Hugging Face cache")
+
+	download.Parse(args)
+	checkOutput(*output)
+
+	if *repoID == "" || *filenames == "" || *repoType == "" {
+		fmt.Println("download subcommand requires repo-id, filenames and repo-type arguments")
+		os.Exit(1)
+	}
+
+	req := api.Request{
+		Type:     "download",
+		RepoID:   *repoID,
+		RepoType: *repoType,
+		Revision: *revision,
+		Token:    *token,
+		Files:       strings.Split(*filenames, ","),
+		NoCache:     *noCache,
+		Concurrency: *concurrency,
+		Connections: *connections,
+		Output:      *output,
+	}
+	serve(ctx, req)
+}
+
+func handleSnapshot(ctx context.Context, args []string) {
+	snapshot := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	repoID := snapshot.String("repo-id", "", "Repository ID")
+	repoType := repoTypeFlag(snapshot)
+	token := snapshot.String("token", "", "User Access Token")
+	output := outputFlag(snapshot)
+	concurrency := concurrencyFlag(snapshot, "Number of files to transfer at once")
+	connections := snapshot.Int("connections", 1, "Number of parallel connections for each large file")
+	revision := snapshot.String("revision", "", "Branch, tag or commit hash")
+	include := snapshot.String("include", "", "Comma-separated glob patterns of files to download")
+	exclude := snapshot.String("exclude", "", "Comma-separated glob patterns of files to skip")
+	localDir := snapshot.String("local-dir", "", "Directory to download to")
+
+	snapshot.Parse(args)
+	checkOutput(*output)
+
+	if *repoID == "" || *repoType == "" {
+		fmt.Println("snapshot subcommand requires repo-id and repo-type arguments")
+		os.Exit(1)
+	}
+
+	req := api.Request{
+		Type:     "snapshot",
+		RepoID:   *repoID,
+		RepoType: *repoType,
+		Revision: *revision,
+		Token:    *token,
+		Include:     splitPatterns(*include),
+		Exclude:     splitPatterns(*exclude),
+		LocalDir:    *localDir,
+		Concurrency: *concurrency,
+		Connections: *connections,
+		Output:      *output,
+	}
+	serve(ctx, req)
+}
+
+func handleVerify(ctx context.Context, args []string) {
+	verify := flag.NewFlagSet("verify", flag.ExitOnError)
+	repoID := verify.String("repo-id", "", "Repository ID")
+	repoType := repoTypeFlag(verify)
+	token := verify.String("token", "", "User Access Token")
+	output := outputFlag(verify)
+	concurrency := concurrencyFlag(verify, "Number of files to hash at once")
+	revision := verify.String("revision", "", "Branch, tag or commit hash")
+	localDir := verify.String("local-dir", ".", "Directory to check")
 
-	download.Parse(os.Args[2:])
+	verify.Parse(args)
+	checkOutput(*output)
 
-	if *repoID == "" || *filenames == "" || *repoType == "" || *token == "" {
-		fmt.Println("download subcommand requires repo-id, filenames, repo-type, and token arguments")
+	if *repoID == "" || *repoType == "" {
+		fmt.Println("verify subcommand requires repo-id and repo-type arguments")
 		os.Exit(1)
 	}
 
-	files := strings.Split(*filenames, ",")
-	if err := api.ServeRequest("download", *repoID, *repoType, *token, "", "", files, false); err != nil {
-		handleError(err)
+	req := api.Request{
+		Type:        "verify",
+		RepoID:      *repoID,
+		RepoType:    *repoType,
+		Revision:    *revision,
+		Token:       *token,
+		LocalDir:    *localDir,
+		Concurrency: *concurrency,
+		Output:      *output,
 	}
+	serve(ctx, req)
 }
 
-func handleUpload() {
+func handleUpload(ctx context.Context, args []string) {
 	upload := flag.NewFlagSet("upload", flag.ExitOnError)
 	repoID := upload.String("repo-id", "", "Repository ID")
 	filenames := upload.String("filenames", "", "Comma-separated list of filenames")
-	repoType := upload.String("repo-type", "", "Type of the repository")
+	repoType := repoTypeFlag(upload)
 	token := upload.String("token", "", "User Access Token")
+	output := outputFlag(upload)
+	concurrency := concurrencyFlag(upload, "Number of files to transfer at once")
+	revision := upload.String("revision", "", "Branch to commit to")
+	commitMessage := upload.String("commit-message", "", "Summary of the upload commit")
+	commitDescription := upload.String("commit-description", "", "Description of the upload commit")
+	createPR := upload.Bool("create-pr", false, "Open a pull request with the commit instead of committing to the branch")
+	include := upload.String("include", "", "Comma-separated glob patterns of files to upload")
+	exclude := upload.String("exclude", "", "Comma-separated glob patterns of files to skip")
+	dryRun := upload.Bool("dry-run", false, "Only list the files to upload")
+	localRoot := upload.String("local-root", "", "Folder the paths in the repository are relative to")
+	pathInRepo := upload.String("path-in-repo", "", "Folder of the repository to upload to")
+
+	upload.Parse(args)
+	checkOutput(*output)
+
+	if *repoID == "" || *filenames == "" || *repoType == "" {
+		fmt.Println("upload subcommand requires repo-id, filenames and repo-type arguments")
+		os.Exit(1)
+	}
+
+	req := api.Request{
+		Type:              "upload",
+		RepoID:            *repoID,
+		RepoType:          *repoType,
+		Revision:          *revision,
+		Token:             *token,
+		Files:             retrieveFiles(*filenames),
+		CommitMessage:     *commitMessage,
+		CommitDescription: *commitDescription,
+		CreatePR:          *createPR,
+		Include:           splitPatterns(*include),
+		Exclude:           splitPatterns(*exclude),
+		DryRun:            *dryRun,
+		LocalRoot:         *localRoot,
+		PathInRepo:        *pathInRepo,
+		Concurrency:       *concurrency,
+		Output:            *output,
+	}
+	serve(ctx, req)
+}
+
+func handleSync(ctx context.Context, args []string) {
+	sync := flag.NewFlagSet("sync", flag.ExitOnError)
+	repoID := sync.String("repo-id", "", "Repository ID")
+	repoType := repoTypeFlag(sync)
+	revision := sync.String("revision", "", "Branch, tag or commit hash")
+	deleteMissing := sync.Bool("delete", false, "Also delete the files missing from the source")
+	dryRun := sync.Bool("dry-run", false, "Only show the planned changes")
+	yes := sync.Bool("yes", false, "Delete without asking for confirmation")
+	concurrency := concurrencyFlag(sync, "Number of files to hash and transfer at once")
+	token := sync.String("token", "", "User Access Token")
+	output := outputFlag(sync)
+	commitMessage := sync.String("commit-message", "", "Summary of the sync commit")
+	commitDescription := sync.String("commit-description", "", "Description of the sync commit")
+	createPR := sync.Bool("create-pr", false, "Open a pull request with the commit instead of committing to the branch")
 
-	upload.Parse(os.Args[2:])
+	words := parseWords(sync, args)
+	checkOutput(*output)
 
-	if *repoID == "" || *filenames == "" || *repoType == "" || *token == "" {
-		fmt.Println("upload subcommand requires repo-id, filenames, repo-type, and token arguments")
+	if len(words) != 2 || (words[0] != api.SyncPush && words[0] != api.SyncPull) {
+		fmt.Println("sync subcommand requires push <dir> or pull <dir>")
+		os.Exit(1)
+	}
+	if *repoID == "" || *repoType == "" {
+		fmt.Println("sync subcommand requires repo-id and repo-type arguments")
 		os.Exit(1)
 	}
 
-	files := retrieveFiles(*filenames)
-	if err := api.ServeRequest("upload", *repoID, *repoType, *token, "", "", files, false); err != nil {
-		handleError(err)
+	req := api.Request{
+		Type:              "sync",
+		RepoID:            *repoID,
+		RepoType:          *repoType,
+		Revision:          *revision,
+		Token:             *token,
+		Action:            words[0],
+		LocalDir:          words[1],
+		Delete:            *deleteMissing,
+		Yes:               *yes,
+		Confirm:           confirm,
+		DryRun:            *dryRun,
+		CommitMessage:     *commitMessage,
+		CommitDescription: *commitDescription,
+		CreatePR:          *createPR,
+		Concurrency:       *concurrency,
+		Output:            *output,
 	}
+	serve(ctx, req)
 }
 
-func handleRepo() {
+func handleRepo(ctx context.Context, args []string) {
 	repo := flag.NewFlagSet("repo", flag.ExitOnError)
 	repoID := repo.String("repo-id", "", "Repository ID")
-	repoType := repo.String("repo-type", "", "Type of the repository")
+	repoType := repoTypeFlag(repo)
 	action := repo.String("action", "", "Action to perform on repo files")
 	token := repo.String("token", "", "User Access Token")
+	output := outputFlag(repo)
 	private := repo.Bool("private", false, "Flag for private repositories")
+	visibility := repo.String("visibility", "", "New visibility of the repository: public or private")
+	gated := repo.String("gated", "", "Gated access: auto, manual or false")
+	toRepoID := repo.String("to-repo-id", "", "New repository ID of a move")
+	dryRun := repo.Bool("dry-run", true, "Only show what a move would do")
+	yes := repo.Bool("yes", false, "Move without asking for confirmation")
 
-	repo.Parse(os.Args[2:])
+	repo.Parse(args)
+	checkOutput(*output)
 
-	if *repoID == "" || *repoType == "" || *action == "" || *token == "" {
-		fmt.Println("repo subcommand requires repo-id, repo-type, action, and token arguments")
+	if *repoID == "" || *repoType == "" || *action == "" {
+		fmt.Println("repo subcommand requires repo-id, repo-type and action arguments")
 		os.Exit(1)
 	}
+	if *action == "move" {
+		if *toRepoID == "" {
+			fmt.Println("repo -action move requires the to-repo-id argument")
+			os.Exit(1)
+		}
+		if !*dryRun && !*yes && !confirm(fmt.Sprintf("Move %s/%s to %s/%s?", *repoType, *repoID, *repoType, *toRepoID)) {
+			fmt.Println("Move cancelled")
+			os.Exit(1)
+		}
+	}
+
+	req := api.Request{
+		Type:     "repo",
+		RepoID:   *repoID,
+		RepoType: *repoType,
+		Token:    *token,
+		Action:   *action,
+		Private:  *private,
+		Output:   *output,
 
-	if err := api.ServeRequest("repo", *repoID, *repoType, *token, *action, "", nil, *private); err != nil {
-		handleError(err)
+		Visibility: *visibility,
+		Gated:      *gated,
+		ToRepoID:   *toRepoID,
+		DryRun:     *dryRun,
 	}
+	serve(ctx, req)
 }
 
-func handleRepoFiles() {
+func handleRepoFiles(ctx context.Context, args []string) {
 	repoFiles := flag.NewFlagSet("repo-files", flag.ExitOnError)
 	repoID := repoFiles.String("repo-id", "", "Repository ID")
-	repoType := repoFiles.String("repo-type", "", "Type of the repository")
+	repoType := repoTypeFlag(repoFiles)
 	action := repoFiles.String("action", "", "Action to perform on repo files")
 	file := repoFiles.String("file", "", "File to do some action with. Optionally, you can pass a directory here")
 	token := repoFiles.String("token", "", "User Access Token")
+	output := outputFlag(repoFiles)
+	revision := repoFiles.String("revision", "", "Branch, tag or commit hash")
+	commitMessage := repoFiles.String("commit-message", "", "Summary of the delete commit")
+
+	repoFiles.Parse(args)
+	checkOutput(*output)
+
+	if *repoID == "" || *repoType == "" || *action == "" {
+		fmt.Println("repo-files subcommand requires repo-id, repo-type and action arguments")
+		os.Exit(1)
+	}
 
-	repoFiles.Parse(os.Args[2:])
+	req := api.Request{
+		Type:     "repo-files",
+		RepoID:   *repoID,
+		RepoType: *repoType,
+		Revision: *revision,
+		Token:    *token,
+		Action:   *action,
+		Files:    retrieveFiles(*file),
+		Output:   *output,
 
-	if *repoID == "" || *repoType == "" || *action == "" || *token == "" {
-		fmt.Println("repo-files subcommand requires repo-id, repo-type, token, and action arguments")
+		CommitMessage: *commitMessage,
+	}
+	serve(ctx, req)
+}
+
+func handleRefs(ctx context.Context, args []string) {
+	refs := flag.NewFlagSet("refs", flag.ExitOnError)
+	repoID := refs.String("repo-id", "", "Repository ID")
+	repoType := repoTypeFlag(refs)
+	action := refs.String("action", "list", "Action to perform on refs")
+	name := refs.String("name", "", "Name of the branch or tag")
+	revision := refs.String("revision", "", "Revision to branch from or to tag")
+	message := refs.String("message", "", "Message of an annotated tag")
+	token := refs.String("token", "", "User Access Token")
+	output := outputFlag(refs)
+
+	refs.Parse(args)
+	checkOutput(*output)
+
+	if *repoID == "" || *repoType == "" {
+		fmt.Println("refs subcommand requires repo-id and repo-type arguments")
+		os.Exit(1)
+	}
+	if *action != "list" && *name == "" {
+		fmt.Printf("refs -action %s requires the name argument\n", *action)
 		os.Exit(1)
 	}
 
-	files := retrieveFiles(*file)
-	if err := api.ServeRequest("repo-files", *repoID, *repoType, *token, *action, "", files, false); err != nil {
-		handleError(err)
+	req := api.Request{
+		Type:     "refs",
+		RepoID:   *repoID,
+		RepoType: *repoType,
+		Revision: *revision,
+		Token:    *token,
+		Action:   *action,
+		Ref:      *name,
+		Message:  *message,
+		Output:   *output,
 	}
+	serve(ctx, req)
+}
+
+func handleLogin(ctx context.Context, args []string) {
+	login := flag.NewFlagSet("login", flag.ExitOnError)
+	token := login.String("token", "", "User Access Token")
+	output := outputFlag(login)
+
+	login.Parse(args)
+	checkOutput(*output)
+
+	if *token == "" {
+		var err error
+		if *token, err = readToken(); err != nil {
+			handleError(ctx, err)
+		}
+	}
+	if *token == "" {
+		fmt.Println("login subcommand requires a token")
+		os.Exit(1)
+	}
+
+	req := api.Request{
+		Type:   "login",
+		Token:  *token,
+		Output: *output,
+	}
+	serve(ctx, req)
+}
+
+func handleLogout(ctx context.Context, args []string) {
+	logout := flag.NewFlagSet("logout", flag.ExitOnError)
+	logout.Parse(args)
+
+	serve(ctx, api.Request{Type: "logout"})
+}
+
+func handleWhoAmI(ctx context.Context, args []string) {
+	whoami := flag.NewFlagSet("whoami", flag.ExitOnError)
+	token := whoami.String("token", "", "User Access Token")
+	output := outputFlag(whoami)
+
+	whoami.Parse(args)
+	checkOutput(*output)
+
+	req := api.Request{
+		Type:   "whoami",
+		Token:  *token,
+		Output: *output,
+	}
+	serve(ctx, req)
+}
+
+func handleConfig(ctx context.Context, profileName string, args []string) {
+	config := flag.NewFlagSet("config", flag.ExitOnError)
+	output := outputFlag(config)
+
+	words := parseWords(config, args)
+	checkOutput(*output)
+
+	req := api.Request{
+		Type:    "config",
+		Profile: profileName,
+		Output:  *output,
+	}
+	args = words
+	switch {
+	case len(args) == 1 && args[0] == "list":
+	case len(args) == 2 && args[0] == "get":
+		req.Key = args[1]
+	case len(args) == 3 && args[0] == "set":
+		req.Key, req.Value = args[1], args[2]
+	default:
+		fmt.Println("config subcommand requires list, get <setting> or set <setting> <value>")
+		os.Exit(1)
+	}
+	req.Action = args[0]
+	serve(ctx, req)
+}
+
+func handleLog(ctx context.Context, args []string) {
+	logf := flag.NewFlagSet("log", flag.ExitOnError)
+	repoID := logf.String("repo-id", "", "Repository ID")
+	repoType := repoTypeFlag(logf)
+	revision := logf.String("revision", "", "Revision to list the history of")
+	path := logf.String("path", "", "Only list the commits that touched this file or folder")
+	limit := logf.Int("limit", 20, "Maximum number of commits to list, 0 for all")
+	token := logf.String("token", "", "User Access Token")
+	output := outputFlag(logf)
+
+	words := parseWords(logf, args)
+	checkOutput(*output)
+
+	if *repoID == "" || *repoType == "" {
+		fmt.Println("log subcommand requires repo-id and repo-type arguments")
+		os.Exit(1)
+	}
+
+	req := api.Request{
+		Type:     "log",
+		RepoID:   *repoID,
+		RepoType: *repoType,
+		Revision: *revision,
+		Token:    *token,
+		Action:   "list",
+		Path:     *path,
+		Limit:    *limit,
+		Output:   *output,
+	}
+	switch {
+	case len(words) == 0:
+	case len(words) == 2 && words[0] == "show":
+		req.Action, req.Commit = "show", words[1]
+	default:
+		fmt.Println("log subcommand takes no argument, or show <commit>")
+		os.Exit(1)
+	}
+	serve(ctx, req)
+}
+
+func handleDiscussions(ctx context.Context, args []string) {
+	discussions := flag.NewFlagSet("discussions", flag.ExitOnError)
+	repoID := discussions.String("repo-id", "", "Repository ID")
+	repoType := repoTypeFlag(discussions)
+	status := discussions.String("status", "all", "List the open, closed or all discussions")
+	discussionType := discussions.String("type", "all", "List the discussion, pull_request or all discussions")
+	author := discussions.String("author", "", "List the discussions opened by this user")
+	title := discussions.String("title", "", "Title of the new discussion")
+	pr := discussions.Bool("pr", false, "Open a pull request rather than a discussion")
+	message := discussions.String("message", "", "Description of a new discussion, or comment to post")
+	token := discussions.String("token", "", "User Access Token")
+	output := outputFlag(discussions)
+
+	words := parseWords(discussions, args)
+	checkOutput(*output)
+
+	if *repoID == "" || *repoType == "" {
+		fmt.Println("discussions subcommand requires repo-id and repo-type arguments")
+		os.Exit(1)
+	}
+
+	req := api.Request{
+		Type:           "discussions",
+		RepoID:         *repoID,
+		RepoType:       *repoType,
+		Token:          *token,
+		Action:         "list",
+		Title:          *title,
+		Message:        *message,
+		CreatePR:       *pr,
+		Status:         *status,
+		DiscussionType: *discussionType,
+		Author:         *author,
+		Output:         *output,
+	}
+	if len(words) > 0 {
+		req.Action = words[0]
+	}
+	switch {
+	case len(words) <= 1 && req.Action == "list":
+	case len(words) == 1 && req.Action == "open" && *title != "":
+	case len(words) == 2 && (req.Action == "view" || req.Action == "open" || req.Action == "comment" || req.Action == "close" || req.Action == "merge"):
+		num, err := strconv.Atoi(strings.TrimPrefix(words[1], "#"))
+		if err != nil || num <= 0 {
+			fmt.Printf("invalid discussion number: %s\n", words[1])
+			os.Exit(1)
+		}
+		req.Num = num
+	default:
+		fmt.Println("discussions subcommand requires list, view <num>, open -title <title>, open <num>, comment <num>, close <num> or merge <num>")
+		os.Exit(1)
+	}
+	if req.Action == "comment" && *message == "" {
+		fmt.Println("discussions comment requires the message argument")
+		os.Exit(1)
+	}
+	serve(ctx, req)
+}
+
+// parseWords parses the flags of fs in args, which may come before, between
+// or after the other words, and returns these words.
+func parseWords(fs *flag.FlagSet, args []string) []string {
+	var words []string
+	for fs.Parse(args); fs.NArg() > 0; fs.Parse(args) {
+		words = append(words, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	return words
+}
+
+// confirm asks question on the terminal and reports whether the answer is
+// yes. Without a terminal there is nobody to ask and the answer is no.
+func confirm(question string) bool {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		huggerLog.Warn("stdin is not a terminal, pass -yes to confirm")
+		return false
+	}
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// readToken prompts for a token without echoing it, or reads it from stdin
+// when stdin is not a terminal, e.g. `echo $TOKEN | hugger login`.
+func readToken() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "Token (from https://huggingface.co/settings/tokens): ")
+		token, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return strings.TrimSpace(string(token)), err
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// serve runs req with the settings of the profile, and exits if it fails.
+func serve(ctx context.Context, req api.Request) {
+	req.Theme = profile.Theme
+	if color.NoColor {
+		// NO_COLOR is set or stdout is not a terminal
+		req.Theme = api.ThemePlain
+	}
+	if err := api.Serve(ctx, req, clientOptions(req.Token)...); err != nil {
+		handleError(ctx, err)
+	}
+}
+
+// clientOptions configures the API client the same way for every subcommand.
+// token is the -token flag, which takes precedence over the token of the profile.
+func clientOptions(token string) []api.ClientOption {
+	policy := api.DefaultRetryPolicy
+	policy.OnRetry = func(e api.RetryEvent) {
+		huggerLog.Warn(fmt.Sprintf("attempt %d failed: %v; retrying in %s",
+			e.Attempt, api.RedactToken(e.Err.Error()), e.Wait.Round(time.Second/10)))
+	}
+	opts := []api.ClientOption{api.WithRetryPolicy(policy), api.WithTokenSource(profile.TokenSource(token))}
+	if profile.Endpoint != "" {
+		opts = append(opts, api.WithEndpoint(profile.Endpoint))
+	}
+	return opts
+}
+
+// outputFlag registers the -output flag of a subcommand.
+func outputFlag(fs *flag.FlagSet) *string {
+	output := api.OutputTable
+	if profile.Output != "" {
+		output = profile.Output
+	}
+	return fs.String("output", output, "Output format: table, json, yaml or csv")
+}
+
+// repoTypeFlag registers the -repo-type flag, defaulting to the repo-type of the profile.
+func repoTypeFlag(fs *flag.FlagSet) *string {
+	return fs.String("repo-type", profile.RepoType, "Type of the repository")
+}
+
+// concurrencyFlag registers the -concurrency flag, defaulting to the concurrency of the profile.
+func concurrencyFlag(fs *flag.FlagSet, usage string) *int {
+	concurrency := defaultConcurrency
+	if profile.Concurrency > 0 {
+		concurrency = profile.Concurrency
+	}
+	return fs.Int("concurrency", concurrency, usage)
+}
+
+// checkOutput exits on an unknown output format, and keeps the messages of a
+// machine-readable one free of colours.
+func checkOutput(output string) {
+	machine, err := api.IsMachineOutput(output)
+	if err != nil {
+		huggerLog.Error(err.Error())
+		os.Exit(exitFailure)
+	}
+	if machine {
+		huggerLog.NoColor = true
+		color.NoColor = true
+	}
+}
+
+// splitPatterns splits a comma-separated list of glob patterns, dropping empty ones.
+func splitPatterns(patterns string) []string {
+	var res []string
+	for _, p := range strings.Split(patterns, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			res = append(res, p)
+		}
+	}
+	return res
 }
 
 func retrieveFiles(filenames string) []string {
@@ -237,31 +997,48 @@ func retrieveFiles(filenames string) []string {
 	return res
 }
 
+// listAllFiles lists the files below folder but the .git folder, and what
+// the .huggerignore files of folder and its subfolders ignore.
 func listAllFiles(folder string) []string {
-	var res []string
-
-	err := filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if path != folder {
-			res = append(res, path)
-		}
-		return nil
+	files, _, err := api.ListLocalFiles(folder, func(err error) {
+		huggerLog.Warn(err.Error())
 	})
 	if err != nil {
 		return nil // Return nil on error
 	}
+	res := make([]string, len(files))
+	for i, file := range files {
+		res[i] = filepath.Join(folder, filepath.FromSlash(file))
+	}
 	return res
 }
 
-func handleError(err error) {
-	var e HError
-	if nerr := json.Unmarshal([]byte(err.Error()), &e); nerr != nil {
-		huggerLog.Error( err.Error() ) //fmt.Println("Error:", err)
-	} else {
-		huggerLog.Error(e.Error)
+func handleError(ctx context.Context, err error) {
+	if ctx.Err() != nil {
+		huggerLog.Error("interrupted, transfer cancelled")
+		os.Exit(exitInterrupted)
+	}
+
+	huggerLog.Error(api.RedactToken(err.Error()))
+	os.Exit(exitCode(err))
+}
+
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, api.ErrUnauthorized):
+		return exitUnauthorized
+	case errors.Is(err, api.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, api.ErrGated):
+		return exitGated
+	case errors.Is(err, api.ErrRateLimited):
+		return exitRateLimited
+	case errors.Is(err, api.ErrConflict):
+		return exitConflict
+	case errors.Is(err, api.ErrChecksumMismatch):
+		return exitChecksum
 	}
+	return exitFailure
 }
 
 func isTerminal() bool {
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	api "hugger/apiv2"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{errors.New("boom"), exitFailure},
+		{&api.HubError{StatusCode: 401}, exitUnauthorized},
+		{&api.HubError{StatusCode: 404}, exitNotFound},
+		{fmt.Errorf("failed to download file: %w", &api.HubError{StatusCode: 404, Code: "EntryNotFound"}), exitNotFound},
+		{&api.HubError{StatusCode: 403, Code: "GatedRepo"}, exitGated},
+		{&api.HubError{StatusCode: 429}, exitRateLimited},
+		{&api.HubError{StatusCode: 409}, exitConflict},
+		{&api.HubError{StatusCode: 500}, exitFailure},
+		{&api.ChecksumError{Path: "a.bin", Algorithm: "sha256"}, exitChecksum},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
@@ -1,11 +1,30 @@
 package log
 import (
 	"fmt"
+	"os"
 )
 
+// NoColor drops the ANSI colours of the messages, e.g. when the output is
+// read by a script rather than a terminal.
+var NoColor = false
+
+// Messages go to stderr so that they never mix with the results on stdout.
 func Error( msg string ) {
 	errorMsg := "\x1b[31;1m[ERROR]"
 	errorMsg += "\033[0m"
+	if NoColor {
+		errorMsg = "[ERROR]"
+	}
+
+	fmt.Fprintln( os.Stderr, errorMsg, msg )
+}
+
+func Warn( msg string ) {
+	warnMsg := "\x1b[33;1m[WARN]"
+	warnMsg += "\033[0m"
+	if NoColor {
+		warnMsg = "[WARN]"
+	}
 
-	fmt.Println( errorMsg, msg )
+	fmt.Fprintln( os.Stderr, warnMsg, msg )
 }